@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"github.com/davidjspooner/cicd-utilities/internal/github"
 	"github.com/davidjspooner/cicd-utilities/internal/man"
 	"github.com/davidjspooner/cicd-utilities/internal/template"
+	"github.com/davidjspooner/cicd-utilities/internal/versionfiles"
 	"github.com/davidjspooner/cicd-utilities/pkg/command"
 )
 
@@ -52,6 +54,7 @@ func main() {
 	githubCommands := github.Commands()
 	templateCommands := template.Commands()
 	manCommands := man.Commands()
+	versionFilesCommands := versionfiles.Commands()
 
 	subcommands := command.RootCommand.SubCommands()
 	subcommands.MustAdd(
@@ -61,11 +64,19 @@ func main() {
 		githubCommands,
 		manCommands,
 		templateCommands,
+		versionFilesCommands,
 	)
 
-	err := command.Run(context.Background(), os.Args[1:])
+	ctx := git.WithPullRequestOpener(context.Background(), github.PullRequests{})
+	err := command.Run(ctx, os.Args[1:])
 	if err != nil {
 		fmt.Printf("Error: %s\n", err)
+		// pass on the exit status of a command run for the user, such as
+		// the one git worktree-exec runs
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			os.Exit(exitErr.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
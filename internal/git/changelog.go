@@ -0,0 +1,216 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+type changelogEntry struct {
+	Type        string   `json:"type"`
+	Scope       string   `json:"scope,omitempty"`
+	Description string   `json:"description"`
+	Breaking    bool     `json:"breaking,omitempty"`
+	Hash        string   `json:"hash"`
+	ShortHash   string   `json:"short_hash"`
+	Issues      []string `json:"issues,omitempty"`
+}
+
+type changelogSection struct {
+	Title   string           `json:"title"`
+	Entries []changelogEntry `json:"entries"`
+}
+
+type changelog struct {
+	Version  string             `json:"version"`
+	Date     string             `json:"date"`
+	From     string             `json:"from,omitempty"`
+	To       string             `json:"to"`
+	Sections []changelogSection `json:"sections"`
+}
+
+// changelogGroups maps commit types to section titles, in display order.
+// Breaking changes are always listed first whatever their type.
+var changelogGroups = []struct {
+	Title string
+	Types []string
+}{
+	{"Features", []string{"feat"}},
+	{"Fixes", []string{"fix"}},
+	{"Performance", []string{"perf"}},
+	{"Reverts", []string{"revert"}},
+}
+
+const breakingChangesTitle = "Breaking Changes"
+
+var issueRefFmt = regexp.MustCompile(`#(\d+)\b`)
+
+func buildChangelog(version string, date time.Time, from, to string, commits []Commit) changelog {
+	cl := changelog{
+		Version: version,
+		Date:    date.Format(time.DateOnly),
+		From:    from,
+		To:      to,
+	}
+	sections := map[string]*changelogSection{}
+	add := func(title string, entry changelogEntry) {
+		section, ok := sections[title]
+		if !ok {
+			section = &changelogSection{Title: title}
+			sections[title] = section
+		}
+		section.Entries = append(section.Entries, entry)
+	}
+
+	// git log lists newest first, the changelog reads better oldest first
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
+		parsed, err := semantic.ParseCommit(commit.Message)
+		if err != nil {
+			continue
+		}
+		entry := changelogEntry{
+			Type:        parsed.Type,
+			Scope:       parsed.Scope,
+			Description: parsed.Description,
+			Breaking:    parsed.Breaking,
+			Hash:        commit.Hash,
+			ShortHash:   commit.ShortHash,
+			Issues:      issueRefs(parsed),
+		}
+		if parsed.Breaking {
+			add(breakingChangesTitle, entry)
+			continue
+		}
+		for _, group := range changelogGroups {
+			for _, t := range group.Types {
+				if t == parsed.Type {
+					add(group.Title, entry)
+				}
+			}
+		}
+	}
+
+	if section, ok := sections[breakingChangesTitle]; ok {
+		cl.Sections = append(cl.Sections, *section)
+	}
+	for _, group := range changelogGroups {
+		if section, ok := sections[group.Title]; ok {
+			cl.Sections = append(cl.Sections, *section)
+		}
+	}
+	return cl
+}
+
+func issueRefs(c semantic.Commit) []string {
+	var issues []string
+	seen := map[string]bool{}
+	texts := []string{c.Description}
+	for _, footer := range c.Footers {
+		texts = append(texts, footer.Value)
+	}
+	for _, text := range texts {
+		for _, match := range issueRefFmt.FindAllStringSubmatch(text, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				issues = append(issues, match[1])
+			}
+		}
+	}
+	return issues
+}
+
+func (cl changelog) IsEmpty() bool {
+	return len(cl.Sections) == 0
+}
+
+// Markdown renders the changelog as a Keep-a-Changelog release section.
+// When repoURL is set commits and issues are linked.
+func (cl changelog) Markdown(repoURL string) string {
+	repoURL = strings.TrimSuffix(repoURL, "/")
+	sb := strings.Builder{}
+	if cl.Version == "Unreleased" {
+		sb.WriteString("## [Unreleased]\n")
+	} else {
+		fmt.Fprintf(&sb, "## [%s] - %s\n", cl.Version, cl.Date)
+	}
+	if cl.IsEmpty() {
+		sb.WriteString("\nNo notable changes.\n")
+	}
+	for _, section := range cl.Sections {
+		fmt.Fprintf(&sb, "\n### %s\n\n", section.Title)
+		for _, entry := range section.Entries {
+			sb.WriteString("- ")
+			if entry.Scope != "" {
+				fmt.Fprintf(&sb, "**%s:** ", entry.Scope)
+			}
+			sb.WriteString(entry.Description)
+			if repoURL != "" {
+				fmt.Fprintf(&sb, " ([%s](%s/commit/%s))", entry.ShortHash, repoURL, entry.Hash)
+			} else {
+				fmt.Fprintf(&sb, " (%s)", entry.ShortHash)
+			}
+			for _, issue := range entry.Issues {
+				if repoURL != "" {
+					fmt.Fprintf(&sb, " ([#%s](%s/issues/%s))", issue, repoURL, issue)
+				} else if !strings.Contains(entry.Description, "#"+issue) {
+					fmt.Fprintf(&sb, " (#%s)", issue)
+				}
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func (cl changelog) JSON() (string, error) {
+	data, err := json.MarshalIndent(cl, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal changelog: %v", err)
+	}
+	return string(data), nil
+}
+
+const keepAChangelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+`
+
+// prependChangelogSection inserts section above the newest release in an
+// existing Keep-a-Changelog document, below any [Unreleased] section, leaving
+// older sections untouched.
+func prependChangelogSection(existing, version, section string) (string, error) {
+	if strings.TrimSpace(existing) == "" {
+		existing = keepAChangelogHeader
+	}
+	lines := strings.SplitAfter(existing, "\n")
+	heading := fmt.Sprintf("## [%s]", version)
+	insertAt := -1
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "## ") {
+			continue
+		}
+		if strings.HasPrefix(line, heading) {
+			return "", fmt.Errorf("changelog already has a section for %s", version)
+		}
+		if insertAt < 0 && !strings.HasPrefix(line, "## [Unreleased]") {
+			insertAt = i
+		}
+	}
+	if insertAt < 0 {
+		if !strings.HasSuffix(existing, "\n") {
+			existing += "\n"
+		}
+		return existing + "\n" + section, nil
+	}
+	before := strings.Join(lines[:insertAt], "")
+	after := strings.Join(lines[insertAt:], "")
+	return before + section + "\n" + after, nil
+}
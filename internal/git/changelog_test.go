@@ -0,0 +1,139 @@
+package git
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildChangelog(t *testing.T) {
+	// newest first, as git log lists them
+	commits := []Commit{
+		{Hash: "4444444444", ShortHash: "4444444", Message: "docs: update the readme"},
+		{Hash: "3333333333", ShortHash: "3333333", Message: "feat!: drop the v1 API"},
+		{Hash: "2222222222", ShortHash: "2222222", Message: "fix(cli): handle empty input\n\nCloses: #12"},
+		{Hash: "1111111111", ShortHash: "1111111", Message: "feat: add the export command (#7)"},
+		{Hash: "0000000000", ShortHash: "0000000", Message: "not a conventional commit"},
+	}
+	date := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cl := buildChangelog("v2.0.0", date, "v1.0.0", "HEAD", commits)
+
+	if cl.Date != "2025-03-01" || cl.IsEmpty() {
+		t.Fatalf("unexpected changelog %+v", cl)
+	}
+	var titles []string
+	for _, section := range cl.Sections {
+		titles = append(titles, section.Title)
+	}
+	if strings.Join(titles, ",") != "Breaking Changes,Features,Fixes" {
+		t.Errorf("expected breaking changes, features then fixes, got %v", titles)
+	}
+
+	testcases := []struct {
+		repoURL  string
+		expected string
+	}{
+		{"", `## [v2.0.0] - 2025-03-01
+
+### Breaking Changes
+
+- drop the v1 API (3333333)
+
+### Features
+
+- add the export command (#7) (1111111)
+
+### Fixes
+
+- **cli:** handle empty input (2222222) (#12)
+`},
+		{"https://github.com/example/app/", `## [v2.0.0] - 2025-03-01
+
+### Breaking Changes
+
+- drop the v1 API ([3333333](https://github.com/example/app/commit/3333333333))
+
+### Features
+
+- add the export command (#7) ([1111111](https://github.com/example/app/commit/1111111111)) ([#7](https://github.com/example/app/issues/7))
+
+### Fixes
+
+- **cli:** handle empty input ([2222222](https://github.com/example/app/commit/2222222222)) ([#12](https://github.com/example/app/issues/12))
+`},
+	}
+	for _, tc := range testcases {
+		if markdown := cl.Markdown(tc.repoURL); markdown != tc.expected {
+			t.Errorf("%q: expected\n%s\ngot\n%s", tc.repoURL, tc.expected, markdown)
+		}
+	}
+
+	empty := buildChangelog("Unreleased", date, "v2.0.0", "HEAD", commits[:1])
+	if markdown := empty.Markdown(""); markdown != "## [Unreleased]\n\nNo notable changes.\n" {
+		t.Errorf("unexpected markdown for an empty changelog:\n%s", markdown)
+	}
+}
+
+func TestPrependChangelogSection(t *testing.T) {
+	section := "## [v1.1.0] - 2025-03-01\n\n### Fixes\n\n- handle empty input (2222222)\n"
+	testcases := []struct {
+		name     string
+		existing string
+		version  string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "empty file",
+			existing: "",
+			version:  "v1.1.0",
+			expected: keepAChangelogHeader + "\n" + section,
+		},
+		{
+			name:     "existing releases",
+			existing: "# Changelog\n\n## [v1.0.0] - 2025-01-01\n\n- first release\n",
+			version:  "v1.1.0",
+			expected: "# Changelog\n\n" + section + "\n## [v1.0.0] - 2025-01-01\n\n- first release\n",
+		},
+		{
+			name:     "unreleased above existing releases",
+			existing: "# Changelog\n\n## [Unreleased]\n\n- work in progress\n\n## [v1.0.0] - 2025-01-01\n\n- first release\n",
+			version:  "v1.1.0",
+			expected: "# Changelog\n\n## [Unreleased]\n\n- work in progress\n\n" + section + "\n## [v1.0.0] - 2025-01-01\n\n- first release\n",
+		},
+		{
+			name:     "only unreleased",
+			existing: "# Changelog\n\n## [Unreleased]\n\n- work in progress",
+			version:  "v1.1.0",
+			expected: "# Changelog\n\n## [Unreleased]\n\n- work in progress\n\n" + section,
+		},
+		{
+			name:     "no release sections",
+			existing: "# Changelog\n",
+			version:  "v1.1.0",
+			expected: "# Changelog\n\n" + section,
+		},
+		{
+			name:     "version already listed",
+			existing: "# Changelog\n\n## [v1.1.0] - 2025-02-01\n\n- already released\n",
+			version:  "v1.1.0",
+			err:      true,
+		},
+	}
+	for _, tc := range testcases {
+		result, err := prependChangelogSection(tc.existing, tc.version, section)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if result != tc.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.name, tc.expected, result)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+type BackportOptions struct {
+	To           string `flag:"--to,Comma separated list of branches to backport to"`
+	BranchPrefix string `flag:"--branch-prefix,Prefix of the backport branch names"`
+	Remote       string `flag:"--remote,Remote to push the backport branches to"`
+	Push         bool   `flag:"--push,Push the backport branches"`
+	PullRequest  bool   `flag:"--pull-request,Open a GitHub pull request for each pushed branch"`
+}
+
+// PullRequestOpener opens pull requests on the service hosting the remote.
+type PullRequestOpener interface {
+	// CheckEnvironment fails when pull requests cannot be opened, so that
+	// callers can fail before doing any work.
+	CheckEnvironment() error
+	// OpenPullRequest asks to merge head into base and returns the URL of
+	// the pull request.
+	OpenPullRequest(ctx context.Context, title, body, head, base string) (string, error)
+}
+
+type pullRequestOpenerKey struct{}
+
+// WithPullRequestOpener returns a context whose commands open pull requests
+// with opener.
+func WithPullRequestOpener(ctx context.Context, opener PullRequestOpener) context.Context {
+	return context.WithValue(ctx, pullRequestOpenerKey{}, opener)
+}
+
+func pullRequestOpenerFromContext(ctx context.Context) (PullRequestOpener, error) {
+	if opener, ok := ctx.Value(pullRequestOpenerKey{}).(PullRequestOpener); ok {
+		return opener, nil
+	}
+	return nil, fmt.Errorf("opening pull requests is not supported here")
+}
+
+// backportResult is the outcome of backporting to one target branch.
+type backportResult struct {
+	Target string
+	Branch string
+	// Remaining holds the commits still to pick after a conflict.
+	Remaining []Commit
+	Err       error
+	URL       string
+}
+
+// executeBackport cherry-picks commits onto a new branch per target. A
+// target that conflicts is left for manual resolution and the rest carry on.
+func executeBackport(ctx context.Context, option *BackportOptions, args []string) error {
+	if option.BranchPrefix == "" {
+		option.BranchPrefix = "backport/"
+	}
+	if option.Remote == "" {
+		option.Remote = defaultRemote
+	}
+	targets := splitList(option.To)
+	if len(args) == 0 {
+		return fmt.Errorf("no commits given")
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("--to is required")
+	}
+	var opener PullRequestOpener
+	if option.PullRequest {
+		if !option.Push {
+			return fmt.Errorf("--pull-request requires --push")
+		}
+		var err error
+		if opener, err = pullRequestOpenerFromContext(ctx); err != nil {
+			return err
+		}
+		if err := opener.CheckEnvironment(); err != nil {
+			return err
+		}
+	}
+
+	repo := RepositoryFromContext(ctx)
+	status, err := repo.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, entry := range status {
+		if !entry.IsUntracked() {
+			return fmt.Errorf("the work tree has uncommitted changes, commit or stash them before backporting")
+		}
+	}
+
+	var commits []Commit
+	for _, arg := range args {
+		log, err := repo.Log(ctx, LogOptions{Range: arg, MaxCount: 1})
+		if err != nil {
+			return err
+		}
+		if len(log) == 0 {
+			return fmt.Errorf("unknown commit %s", arg)
+		}
+		if log[0].IsMerge() {
+			return fmt.Errorf("%s is a merge commit, backport the commits it merged instead", log[0].ShortHash)
+		}
+		commits = append(commits, log[0])
+	}
+
+	original, err := repo.CurrentBranch(ctx)
+	if err != nil {
+		return err
+	}
+	detached := original == "HEAD"
+	if detached {
+		// CI usually checks out a commit, come back to that commit
+		head, err := repo.Log(ctx, LogOptions{Range: "HEAD", MaxCount: 1})
+		if err != nil {
+			return err
+		}
+		original = head[0].Hash
+	}
+	branches, err := repo.Branches(ctx)
+	if err != nil {
+		return err
+	}
+
+	var results []backportResult
+	for _, target := range targets {
+		result := backport(ctx, repo, option, opener, branches, target, commits)
+		switchBack := repo.Switch
+		if detached {
+			switchBack = repo.Detach
+		}
+		if err := switchBack(ctx, original); err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+
+	failed := 0
+	for _, result := range results {
+		switch {
+		case result.Err == nil:
+			fmt.Println(strings.TrimSpace(fmt.Sprintf("%s: %s %s", result.Target, result.Branch, result.URL)))
+		case len(result.Remaining) > 0:
+			failed++
+			var remaining []string
+			for _, commit := range result.Remaining {
+				remaining = append(remaining, commit.ShortHash)
+			}
+			fmt.Printf("%s: %v\n", result.Target, result.Err)
+			fmt.Printf("  resolve with: git checkout %s && git cherry-pick -x %s\n", result.Branch, strings.Join(remaining, " "))
+		default:
+			failed++
+			fmt.Printf("%s: %v\n", result.Target, result.Err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("backport failed for %d of %d branch(es)", failed, len(results))
+	}
+	return nil
+}
+
+func backport(ctx context.Context, repo Repository, option *BackportOptions, opener PullRequestOpener, branches []string, target string, commits []Commit) backportResult {
+	result := backportResult{
+		Target: target,
+		Branch: backportBranchName(option.BranchPrefix, target, commits),
+	}
+
+	start := target
+	if !slices.Contains(branches, target) {
+		start = option.Remote + "/" + target
+		if !slices.Contains(branches, start) {
+			result.Err = fmt.Errorf("branch %s does not exist", target)
+			return result
+		}
+	}
+	if slices.Contains(branches, result.Branch) {
+		result.Err = fmt.Errorf("branch %s already exists", result.Branch)
+		return result
+	}
+	if err := repo.CreateBranch(ctx, result.Branch, start); err != nil {
+		result.Err = err
+		return result
+	}
+	if err := repo.Switch(ctx, result.Branch); err != nil {
+		result.Err = err
+		return result
+	}
+
+	for i, commit := range commits {
+		if err := repo.CherryPick(ctx, commit.Hash); err != nil {
+			result.Err = err
+			result.Remaining = commits[i:]
+			return result
+		}
+	}
+	slog.Info("Backported", "branch", result.Branch, "onto", start, "commits", len(commits))
+
+	if !option.Push {
+		return result
+	}
+	if result.Err = repo.Push(ctx, option.Remote, PushOptions{}, result.Branch); result.Err != nil {
+		return result
+	}
+	if opener != nil {
+		title, body := backportPullRequest(target, commits)
+		result.URL, result.Err = opener.OpenPullRequest(ctx, title, body, result.Branch, target)
+	}
+	return result
+}
+
+// backportBranchName names the branch after the first commit and the
+// target, e.g. backport/abc1234-release-1.3.
+func backportBranchName(prefix, target string, commits []Commit) string {
+	return prefix + commits[0].ShortHash + "-" + strings.ReplaceAll(target, "/", "-")
+}
+
+// backportPullRequest returns the title and body of the pull request.
+func backportPullRequest(target string, commits []Commit) (string, string) {
+	title := fmt.Sprintf("[%s] Backport %d commits", target, len(commits))
+	if len(commits) == 1 {
+		title = fmt.Sprintf("[%s] %s", target, commits[0].Subject())
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "Backport to %s of:\n\n", target)
+	for _, commit := range commits {
+		fmt.Fprintf(&body, "- %s %s\n", commit.Hash, commit.Subject())
+	}
+	return title, body.String()
+}
@@ -0,0 +1,89 @@
+package git
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBackport(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: initial commit")
+	repo.Checkout("release/1.3")
+	repo.Checkout("release/1.2")
+	repo.Checkout("main")
+	fix := repo.Commit("fix: handle empty input", "main.go")
+	docs := repo.Commit("docs: explain the fix", "README.md")
+	repo.Conflicts["backport/"+fix[:7]+"-release-1.2"] = []string{"main.go"}
+	ctx := WithRepository(context.Background(), repo)
+
+	option := &BackportOptions{To: "release/1.3,release/1.2", BranchPrefix: "backport/", Remote: "origin", Push: true}
+	err := executeBackport(ctx, option, []string{fix, docs})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Fatalf("expected release/1.2 to fail, got %v", err)
+	}
+	if branch, _ := repo.CurrentBranch(ctx); branch != "main" {
+		t.Errorf("expected to be back on main, got %s", branch)
+	}
+
+	branch := "backport/" + fix[:7] + "-release-1.3"
+	commits, err := repo.Log(ctx, LogOptions{Range: "release/1.3.." + branch})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(commits) != 2 || !strings.Contains(commits[1].Message, "(cherry picked from commit "+fix+")") {
+		t.Errorf("expected both commits to be picked with -x, got %v", commits)
+	}
+	if !slices.Equal(repo.Pushed, []string{"origin " + branch}) {
+		t.Errorf("expected only %s to be pushed, got %v", branch, repo.Pushed)
+	}
+
+	if err := executeBackport(ctx, &BackportOptions{To: "release/9.9", BranchPrefix: "backport/"}, []string{fix}); err == nil {
+		t.Errorf("expected an error for a missing target branch")
+	}
+}
+
+type fakePullRequests struct {
+	opened []string
+}
+
+func (f *fakePullRequests) CheckEnvironment() error {
+	return nil
+}
+
+func (f *fakePullRequests) OpenPullRequest(_ context.Context, title, _, head, base string) (string, error) {
+	f.opened = append(f.opened, head+" -> "+base+": "+title)
+	return "https://example.com/pull/1", nil
+}
+
+func TestBackportDetached(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: initial commit")
+	repo.Checkout("release/1.3")
+	repo.Checkout("main")
+	fix := repo.Commit("fix: handle empty input", "main.go")
+	repo.Checkout("HEAD")
+	pulls := &fakePullRequests{}
+	ctx := WithPullRequestOpener(WithRepository(context.Background(), repo), pulls)
+
+	option := &BackportOptions{To: "release/1.3", BranchPrefix: "backport/", Remote: "origin", Push: true, PullRequest: true}
+	if err := executeBackport(ctx, option, []string{fix}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if branch, _ := repo.CurrentBranch(ctx); branch != "HEAD" {
+		t.Errorf("expected a detached HEAD again, got %s", branch)
+	}
+	if head, _ := repo.Log(ctx, LogOptions{Range: "HEAD", MaxCount: 1}); head[0].Hash != fix {
+		t.Errorf("expected HEAD back at %s, got %s", fix, head[0].Hash)
+	}
+	expected := "backport/" + fix[:7] + "-release-1.3 -> release/1.3: [release/1.3] fix: handle empty input"
+	if !slices.Equal(pulls.opened, []string{expected}) {
+		t.Errorf("expected pull request %q, got %v", expected, pulls.opened)
+	}
+
+	option.To = "release/1.4"
+	if err := executeBackport(WithRepository(context.Background(), repo), option, []string{fix}); err == nil {
+		t.Errorf("expected --pull-request to fail without a pull request opener")
+	}
+}
@@ -0,0 +1,271 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+type ChangedOptions struct {
+	Base       string `flag:"--base,Ref to compare with (default: merge-base with the PR base in pull requests, otherwise the previous tag)"`
+	Head       string `flag:"--head,Ref with the changes"`
+	Components string `flag:"--components,JSON file of components whose paths and go_packages map files onto targets"`
+	Format     string `flag:"--format,Output format (json|matrix|targets|files)"`
+}
+
+// changedResult is the json output of git changed.
+type changedResult struct {
+	Base    string   `json:"base"`
+	Head    string   `json:"head"`
+	Files   []string `json:"files"`
+	Targets []string `json:"targets"`
+	// Submodules lists the commits behind each changed submodule pointer.
+	Submodules []submoduleChange `json:"submodules"`
+}
+
+type submoduleChange struct {
+	Path    string   `json:"path"`
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Commits []string `json:"commits"`
+}
+
+func executeChanged(ctx context.Context, option *ChangedOptions, args []string) error {
+	repo := RepositoryFromContext(ctx)
+	if option.Head == "" {
+		option.Head = "HEAD"
+	}
+
+	base := option.Base
+	if base != "" {
+		mergeBase, err := repo.MergeBase(ctx, base, option.Head)
+		if err != nil {
+			return err
+		}
+		base = mergeBase
+	} else {
+		var err error
+		if base, err = defaultChangedBase(ctx, repo, option.Head); err != nil {
+			return err
+		}
+	}
+
+	files, err := repo.ChangedFiles(ctx, base, option.Head)
+	if err != nil {
+		return err
+	}
+	slog.Debug("Changed files", "base", base, "head", option.Head, "files", len(files))
+
+	result := changedResult{Base: base, Head: option.Head, Files: files, Targets: []string{}}
+	if result.Files == nil {
+		result.Files = []string{}
+	}
+	if result.Submodules, err = submoduleChanges(ctx, repo, base, option.Head, files); err != nil {
+		return err
+	}
+	if option.Components != "" {
+		components, err := loadComponents(option.Components, args)
+		if err != nil {
+			return err
+		}
+		goDirs, err := componentGoDirs(ctx, repo, components)
+		if err != nil {
+			return err
+		}
+		result.Targets = affectedTargets(components, goDirs, files)
+	}
+
+	switch option.Format {
+	case "files":
+		for _, file := range result.Files {
+			fmt.Println(file)
+		}
+	case "targets":
+		for _, target := range result.Targets {
+			fmt.Println(target)
+		}
+	case "matrix":
+		// the shape expected by strategy.matrix: ${{ fromJSON(...) }}
+		include := []map[string]string{}
+		for _, target := range result.Targets {
+			include = append(include, map[string]string{"target": target})
+		}
+		data, err := json.Marshal(map[string]any{"include": include})
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "json", "":
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	default:
+		return fmt.Errorf("unknown format %q, expected json, matrix, targets or files", option.Format)
+	}
+	return nil
+}
+
+// submoduleChanges expands the changed files that are submodule pointers
+// into the commits between the old and the new pointer.
+func submoduleChanges(ctx context.Context, repo Repository, base, head string, files []string) ([]submoduleChange, error) {
+	changes := []submoduleChange{}
+	submodules, err := repo.Submodules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, submodule := range submodules {
+		if !slices.Contains(files, submodule.Path) {
+			continue
+		}
+		change := submoduleChange{Path: submodule.Path, Commits: []string{}}
+		if change.From, err = repo.SubmoduleCommit(ctx, base, submodule.Path); err != nil {
+			return nil, err
+		}
+		if change.To, err = repo.SubmoduleCommit(ctx, head, submodule.Path); err != nil {
+			return nil, err
+		}
+		if !submodule.Initialized || change.To == "" {
+			slog.Warn("Cannot list the commits of submodule", "path", submodule.Path, "initialized", submodule.Initialized)
+			changes = append(changes, change)
+			continue
+		}
+		commits, err := submoduleLog(ctx, repo, submodule.Path, change.From, change.To)
+		if err != nil {
+			// e.g. a shallow submodule clone without the old commit
+			slog.Warn("Cannot list the commits of submodule", "path", submodule.Path, "error", err)
+		}
+		for _, commit := range commits {
+			change.Commits = append(change.Commits, commit.ShortHash+" "+commit.Subject())
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// submoduleLog lists the commits of the submodule at path in from..to, or
+// up to to when the submodule was just added.
+func submoduleLog(ctx context.Context, repo Repository, path, from, to string) ([]Commit, error) {
+	sub, err := repo.OpenSubmodule(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	revRange := to
+	if from != "" {
+		revRange = from + ".." + to
+	}
+	return sub.Log(ctx, LogOptions{Range: revRange})
+}
+
+// defaultChangedBase compares a pull request with the merge-base of its
+// base branch, and any other build with the tag before head. Without such a
+// tag everything counts as changed.
+func defaultChangedBase(ctx context.Context, repo Repository, head string) (string, error) {
+	if detectBuild(ctx).PullRequest != "" {
+		return repo.MergeBase(ctx, getDefaultBaseRef(ctx, repo), head)
+	}
+	tag, err := GetLatestTag(ctx, semantic.SemVer, head+"^", "")
+	if err != nil {
+		slog.Info("No previous tag, treating all files as changed", "head", head)
+		return EmptyTree, nil
+	}
+	return tag, nil
+}
+
+// componentGoDirs returns, for each component with go_packages, the
+// repository relative directories of the packages they depend on.
+func componentGoDirs(ctx context.Context, repo Repository, components []Component) (map[string]map[string]bool, error) {
+	dirs := map[string]map[string]bool{}
+	root := ""
+	for _, component := range components {
+		if len(component.GoPackages) == 0 {
+			continue
+		}
+		if root == "" {
+			var err error
+			if root, err = repo.Run(ctx, "rev-parse", "--show-toplevel"); err != nil {
+				return nil, err
+			}
+		}
+		packageDirs, err := goPackageDirs(ctx, root, component.GoPackages)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %v", component.Name, err)
+		}
+		dirs[component.Name] = packageDirs
+	}
+	return dirs, nil
+}
+
+// goPackageDirs lists the directories, relative to root, of the packages
+// matched by patterns and everything they import from the same repository.
+func goPackageDirs(ctx context.Context, root string, patterns []string) (map[string]bool, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"list", "-deps", "-f", "{{if not .Standard}}{{.Dir}}{{end}}"}, patterns...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = root
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list %s failed: %v: %s", strings.Join(patterns, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	dirs := map[string]bool{}
+	for _, dir := range strings.Split(out.String(), "\n") {
+		if dir == "" {
+			continue
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			// dependencies from the module cache
+			continue
+		}
+		dirs[filepath.ToSlash(rel)] = true
+	}
+	return dirs, nil
+}
+
+// affectedTargets returns the components touched by files, through their
+// path globs or, for Go components, a changed package they depend on or a
+// changed go.mod or go.sum.
+func affectedTargets(components []Component, goDirs map[string]map[string]bool, files []string) []string {
+	targets := []string{}
+	for _, component := range components {
+		if isAffected(component, goDirs[component.Name], files) {
+			targets = append(targets, component.Name)
+		}
+	}
+	return targets
+}
+
+func isAffected(component Component, goDirs map[string]bool, files []string) bool {
+	for _, file := range files {
+		for _, pattern := range component.Paths {
+			if matchGlob(pattern, file) {
+				return true
+			}
+		}
+		if len(component.GoPackages) == 0 {
+			continue
+		}
+		switch name := path.Base(file); {
+		case name == "go.mod" || name == "go.sum":
+			return true
+		case strings.HasSuffix(name, ".go") && goDirs[path.Dir(file)]:
+			return true
+		}
+	}
+	return false
+}
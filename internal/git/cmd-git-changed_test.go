@@ -0,0 +1,143 @@
+package git
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestDefaultChangedBase(t *testing.T) {
+	repo := NewFakeRepository()
+	noCI := func(string) string { return "" }
+	ctx := WithGetenv(WithRepository(context.Background(), repo), noCI)
+	repo.Commit("feat: first", "a.txt")
+
+	base, err := defaultChangedBase(ctx, repo, "HEAD")
+	if err != nil || base != EmptyTree {
+		t.Errorf("expected the empty tree without tags, got %s, %v", base, err)
+	}
+
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("fix: second", "b.txt")
+	if err := repo.Tag("v1.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	base, _ = defaultChangedBase(ctx, repo, "HEAD")
+	if base != "v1.0.0" {
+		t.Errorf("expected the tag before HEAD, got %s", base)
+	}
+	files, _ := repo.ChangedFiles(ctx, base, "HEAD")
+	if !slices.Equal(files, []string{"b.txt"}) {
+		t.Errorf("expected b.txt to be changed, got %v", files)
+	}
+}
+
+func TestGetDefaultBaseRef(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Responses["symbolic-ref --quiet refs/remotes/origin/HEAD"] = "refs/remotes/origin/trunk"
+	testcases := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"no CI", nil, "origin/trunk"},
+		{"gitlab merge request", map[string]string{
+			"GITLAB_CI":                           "true",
+			"CI_MERGE_REQUEST_IID":                "5",
+			"CI_MERGE_REQUEST_TARGET_BRANCH_NAME": "develop",
+		}, "origin/develop"},
+		{"azure pull request", map[string]string{
+			"TF_BUILD":                             "True",
+			"SYSTEM_PULLREQUEST_PULLREQUESTNUMBER": "9",
+			"SYSTEM_PULLREQUEST_TARGETBRANCH":      "refs/heads/release/2.x",
+		}, "origin/release/2.x"},
+	}
+	for _, tc := range testcases {
+		ctx := WithGetenv(context.Background(), func(key string) string { return tc.env[key] })
+		if base := getDefaultBaseRef(ctx, repo); base != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, base)
+		}
+	}
+}
+
+func TestAffectedTargets(t *testing.T) {
+	components := []Component{
+		{Name: "api", Paths: []string{"services/api/**"}},
+		{Name: "web", Paths: []string{"web/**", "shared/*.json"}},
+		{Name: "cli", GoPackages: []string{"./cmd/cli"}},
+	}
+	goDirs := map[string]map[string]bool{
+		"cli": {"cmd/cli": true, "internal/config": true},
+	}
+	tests := []struct {
+		files    []string
+		expected []string
+	}{
+		{[]string{"README.md"}, []string{}},
+		{[]string{"services/api/main.go"}, []string{"api"}},
+		{[]string{"shared/theme.json", "internal/config/load.go"}, []string{"web", "cli"}},
+		{[]string{"internal/config/README.md"}, []string{}},
+		{[]string{"go.sum"}, []string{"cli"}},
+	}
+	for _, tc := range tests {
+		got := affectedTargets(components, goDirs, tc.files)
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("%v: expected %v, got %v", tc.files, tc.expected, got)
+		}
+	}
+}
+
+func TestGoPackageDirs(t *testing.T) {
+	dirs, err := goPackageDirs(context.Background(), "testdata/gomod", []string{"./cmd/cli"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, dir := range []string{"cmd/cli", "internal/config"} {
+		if !dirs[dir] {
+			t.Errorf("expected %s in %v", dir, dirs)
+		}
+	}
+	if dirs["internal/unused"] {
+		t.Errorf("unexpected unrelated packages in %v", dirs)
+	}
+}
+
+func TestSubmoduleChanges(t *testing.T) {
+	lib := NewFakeRepository()
+	old := lib.Commit("feat: parser")
+	middle := lib.Commit("fix: parser handles empty input")
+	latest := lib.Commit("feat: parser streams")
+
+	repo := NewFakeRepository()
+	first := repo.Commit("feat: vendor lib", "vendor/lib")
+	second := repo.Commit("chore: update lib", "vendor/lib", "README.md")
+	repo.Gitlinks[first+" vendor/lib"] = old
+	repo.Gitlinks[second+" vendor/lib"] = latest
+	repo.SubmoduleList = []Submodule{{Path: "vendor/lib", Commit: latest, Initialized: true}, {Path: "vendor/other", Initialized: true}}
+	repo.SubmoduleRepos["vendor/lib"] = lib
+	ctx := WithRepository(context.Background(), repo)
+
+	files, _ := repo.ChangedFiles(ctx, first, second)
+	changes, err := submoduleChanges(ctx, repo, first, second, files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].From != old || changes[0].To != latest {
+		t.Fatalf("expected vendor/lib to move from %s to %s, got %+v", old, latest, changes)
+	}
+	expected := []string{latest[:7] + " feat: parser streams", middle[:7] + " fix: parser handles empty input"}
+	if !slices.Equal(changes[0].Commits, expected) {
+		t.Errorf("expected commits %v, got %v", expected, changes[0].Commits)
+	}
+	// a shallow submodule clone without the old commit
+	repo.SubmoduleRepos["vendor/lib"] = NewFakeRepository()
+	changes, err = submoduleChanges(ctx, repo, first, second, files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].To != latest || len(changes[0].Commits) != 0 {
+		t.Errorf("expected vendor/lib without commits, got %+v", changes)
+	}
+}
@@ -0,0 +1,94 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+type ChangelogOptions struct {
+	From    string `flag:"--from,Start of the range (default: the latest version tag before --to)"`
+	To      string `flag:"--to,End of the range"`
+	Version string `flag:"--version,Title of the new section (default: --to when it is a tag, otherwise Unreleased)"`
+	Format  string `flag:"--format,Output format (markdown|json)"`
+	Output  string `flag:"--output,Prepend the section to this Keep-a-Changelog file instead of printing it"`
+	RepoURL string `flag:"--repo-url,Repository URL used to link commits and issues"`
+}
+
+func executeChangelog(ctx context.Context, option *ChangelogOptions, args []string) error {
+	repo := RepositoryFromContext(ctx)
+	to := option.To
+	if to == "" {
+		to = "HEAD"
+	}
+	from := option.From
+	if from == "" {
+		// Search from the parent so a tag on --to itself is not its own start
+		latestTag, err := GetLatestTag(ctx, semantic.SemVer, to+"^", "")
+		if err != nil {
+			slog.Debug("No previous tag, using the full history", "error", err)
+		}
+		from = latestTag
+	}
+
+	revRange := to
+	if from != "" {
+		revRange = from + ".." + to
+	}
+	commits, err := repo.Log(ctx, LogOptions{Range: revRange})
+	if err != nil {
+		return err
+	}
+
+	version := option.Version
+	if version == "" {
+		version = "Unreleased"
+		if to != "HEAD" {
+			if _, err := repo.Run(ctx, "rev-parse", "--verify", "--quiet", "refs/tags/"+to); err == nil {
+				version = to
+			}
+		}
+	}
+	cl := buildChangelog(version, time.Now().UTC(), from, to, commits)
+	slog.Debug("Changelog", "from", from, "to", to, "commits", len(commits), "sections", len(cl.Sections))
+
+	var text string
+	switch option.Format {
+	case "markdown", "":
+		text = cl.Markdown(option.RepoURL)
+	case "json":
+		if option.Output != "" {
+			return fmt.Errorf("--output only supports the markdown format")
+		}
+		text, err = cl.JSON()
+		if err != nil {
+			return err
+		}
+		text += "\n"
+	default:
+		return fmt.Errorf("unsupported --format: %q . Please use 'markdown' or 'json'", option.Format)
+	}
+
+	if option.Output == "" {
+		fmt.Print(text)
+		return nil
+	}
+
+	existing, err := os.ReadFile(option.Output)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %v", option.Output, err)
+	}
+	updated, err := prependChangelogSection(string(existing), version, text)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(option.Output, []byte(updated), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", option.Output, err)
+	}
+	slog.Info("Changelog updated", "file", option.Output, "version", version)
+	return nil
+}
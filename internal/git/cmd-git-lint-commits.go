@@ -0,0 +1,160 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+type LintCommitsOptions struct {
+	Base            string `flag:"--base,Branch the range starts from (merge-base with HEAD, default: the PR base or origin default branch)"`
+	Types           string `flag:"--types,Comma separated list of allowed commit types"`
+	Scopes          string `flag:"--scopes,Comma separated list of allowed scopes (default: any scope)"`
+	MaxHeaderLength int    `flag:"--max-header-length,Maximum length of the header line (-1 for no limit)"`
+	RequireFooters  string `flag:"--require-footers,Comma separated list of footers every commit must have"`
+	InstallHook     bool   `flag:"--install-hook,Write .git/hooks/commit-msg to run this check on every commit"`
+}
+
+const commitMsgHookMarker = "# installed by cicd-utilities git lint-commits"
+
+func executeLintCommits(ctx context.Context, option *LintCommitsOptions, args []string) error {
+	if option.Types == "" {
+		option.Types = strings.Join(semantic.DefaultCommitTypes, ",")
+	}
+	if option.MaxHeaderLength == 0 {
+		option.MaxHeaderLength = 72
+	}
+	rules := semantic.CommitRules{
+		Types:           splitList(option.Types),
+		Scopes:          splitList(option.Scopes),
+		MaxHeaderLength: option.MaxHeaderLength,
+		RequiredFooters: splitList(option.RequireFooters),
+	}
+
+	repo := RepositoryFromContext(ctx)
+	if option.InstallHook {
+		return installCommitMsgHook(ctx, repo, option)
+	}
+
+	if len(args) > 0 && args[0] == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read commit message from stdin: %v", err)
+		}
+		message := cleanupCommitMessage(string(data))
+		if isMergeMessage(message) {
+			return nil
+		}
+		violations := rules.Lint(message)
+		for _, violation := range violations {
+			fmt.Printf("commit message: %s\n", violation)
+		}
+		if len(violations) > 0 {
+			return fmt.Errorf("commit message failed lint")
+		}
+		return nil
+	}
+
+	var revRange string
+	if len(args) > 0 {
+		revRange = args[0]
+	} else {
+		base := option.Base
+		if base == "" {
+			base = getDefaultBaseRef(ctx, repo)
+		}
+		mergeBase, err := repo.MergeBase(ctx, base, "HEAD")
+		if err != nil {
+			return err
+		}
+		revRange = mergeBase + "..HEAD"
+	}
+
+	commits, err := repo.Log(ctx, LogOptions{Range: revRange, NoMerges: true})
+	if err != nil {
+		return err
+	}
+	slog.Debug("Linting commits", "range", revRange, "commits", len(commits))
+
+	failed := 0
+	for _, commit := range commits {
+		violations := rules.Lint(commit.Message)
+		if len(violations) == 0 {
+			continue
+		}
+		failed++
+		fmt.Printf("%s %s\n", commit.ShortHash, commit.Subject())
+		for _, violation := range violations {
+			fmt.Printf("  - %s\n", violation)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d commit message(s) failed lint", failed, len(commits))
+	}
+	slog.Info("All commit messages passed lint", "range", revRange, "commits", len(commits))
+	return nil
+}
+
+// cleanupCommitMessage drops the comment lines and verbose diff that git
+// leaves in the file handed to the commit-msg hook.
+func cleanupCommitMessage(message string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, "# ------------------------ >8 ------------------------") {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func isMergeMessage(message string) bool {
+	return strings.HasPrefix(message, "Merge ")
+}
+
+func installCommitMsgHook(ctx context.Context, repo Repository, option *LintCommitsOptions) error {
+	hookPath, err := repo.Run(ctx, "rev-parse", "--git-path", "hooks/commit-msg")
+	if err != nil {
+		return fmt.Errorf("failed to locate the hooks directory: %v", err)
+	}
+	existing, err := os.ReadFile(hookPath)
+	if err == nil && !strings.Contains(string(existing), commitMsgHookMarker) {
+		return fmt.Errorf("%s already exists and was not installed by this tool, remove it first", hookPath)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the path of this executable: %v", err)
+	}
+	command := []string{shellQuote(exe), "git", "lint-commits"}
+	if option.Types != "" {
+		command = append(command, "--types", shellQuote(option.Types))
+	}
+	if option.Scopes != "" {
+		command = append(command, "--scopes", shellQuote(option.Scopes))
+	}
+	command = append(command, "--max-header-length", strconv.Itoa(option.MaxHeaderLength))
+	if option.RequireFooters != "" {
+		command = append(command, "--require-footers", shellQuote(option.RequireFooters))
+	}
+	hook := fmt.Sprintf("#!/bin/sh\n%s\nexec %s - < \"$1\"\n", commitMsgHookMarker, strings.Join(command, " "))
+
+	if err := os.MkdirAll(filepath.Dir(hookPath), 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %v", err)
+	}
+	if err := os.WriteFile(hookPath, []byte(hook), 0755); err != nil {
+		return fmt.Errorf("failed to write %s: %v", hookPath, err)
+	}
+	slog.Info("Installed commit-msg hook", "path", hookPath)
+	return nil
+}
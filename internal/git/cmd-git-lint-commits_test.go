@@ -0,0 +1,130 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanupCommitMessage(t *testing.T) {
+	testcases := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{"plain", "feat: add export\n\nMore detail.\n", "feat: add export\n\nMore detail."},
+		{"comments", "fix: handle empty input\n# Please enter the commit message for your changes.\n#\n# On branch main\n", "fix: handle empty input"},
+		{"verbose diff", "docs: update readme\n# ------------------------ >8 ------------------------\n# Do not modify or remove the line above.\ndiff --git a/README.md b/README.md\n+# Title\n", "docs: update readme"},
+		{"only comments", "# Please enter the commit message for your changes.\n", ""},
+	}
+	for _, tc := range testcases {
+		if result := cleanupCommitMessage(tc.message); result != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, result)
+		}
+	}
+}
+
+func TestLintCommitsStdin(t *testing.T) {
+	ctx := WithRepository(context.Background(), NewFakeRepository())
+	testcases := []struct {
+		name    string
+		message string
+		err     bool
+	}{
+		{"valid", "feat: add export\n# On branch main\n", false},
+		{"invalid type", "feature: add export\n", true},
+		{"not conventional", "add export\n", true},
+		{"merge", "Merge branch 'main' into feature\n", false},
+		{"header too long", "fix: " + strings.Repeat("x", 80) + "\n", true},
+	}
+	for _, tc := range testcases {
+		path := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+		if err := os.WriteFile(path, []byte(tc.message), 0644); err != nil {
+			t.Fatal(err)
+		}
+		stdin, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := os.Stdin
+		os.Stdin = stdin
+		err = executeLintCommits(ctx, &LintCommitsOptions{Types: "feat,fix", MaxHeaderLength: 72}, []string{"-"})
+		os.Stdin = saved
+		stdin.Close()
+		if tc.err && err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		} else if !tc.err && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestLintCommitsDefaults(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: initial commit")
+	repo.Commit("fix: " + strings.Repeat("x", 80))
+	repo.Commit("feature: not a default type")
+	ctx := WithRepository(context.Background(), repo)
+
+	testcases := []struct {
+		name   string
+		option LintCommitsOptions
+		revs   string
+		err    bool
+	}{
+		{"default header length", LintCommitsOptions{}, "HEAD~2..HEAD~1", true},
+		{"no header limit", LintCommitsOptions{MaxHeaderLength: -1}, "HEAD~2..HEAD~1", false},
+		{"default types", LintCommitsOptions{}, "HEAD~1..HEAD", true},
+		{"custom types", LintCommitsOptions{Types: "feature"}, "HEAD~1..HEAD", false},
+	}
+	for _, tc := range testcases {
+		err := executeLintCommits(ctx, &tc.option, []string{tc.revs})
+		if tc.err && err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		} else if !tc.err && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestInstallCommitMsgHook(t *testing.T) {
+	hookPath := filepath.Join(t.TempDir(), "hooks", "commit-msg")
+	repo := NewFakeRepository()
+	repo.Responses["rev-parse --git-path hooks/commit-msg"] = hookPath
+	ctx := WithRepository(context.Background(), repo)
+
+	option := &LintCommitsOptions{Types: "feat,fix", Scopes: "it's", MaxHeaderLength: 72, InstallHook: true}
+	if err := executeLintCommits(ctx, option, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hook, err := os.ReadFile(hookPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"#!/bin/sh\n", commitMsgHookMarker, "git lint-commits --types 'feat,fix' --scopes 'it'\\''s' --max-header-length 72", ` - < "$1"`} {
+		if !strings.Contains(string(hook), expected) {
+			t.Errorf("expected the hook to contain %q, got:\n%s", expected, hook)
+		}
+	}
+
+	// reinstalling over our own hook is fine
+	if err := executeLintCommits(ctx, option, nil); err != nil {
+		t.Errorf("unexpected error reinstalling the hook: %v", err)
+	}
+
+	if err := os.WriteFile(hookPath, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := executeLintCommits(ctx, option, nil); err == nil {
+		t.Errorf("expected an error for a hook installed by something else")
+	}
+	hook, err = os.ReadFile(hookPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(hook) != "#!/bin/sh\nexit 0\n" {
+		t.Errorf("expected the existing hook to be left alone, got:\n%s", hook)
+	}
+}
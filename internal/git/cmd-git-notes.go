@@ -0,0 +1,228 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/ci"
+)
+
+type NotesOptions struct {
+	Ref    string `flag:"--ref,Notes ref holding the build records"`
+	Remote string `flag:"--remote,Remote to push and fetch the notes ref"`
+}
+
+// setDefaults fills in the options left empty on the command line.
+func (option *NotesOptions) setDefaults() {
+	if option.Ref == "" {
+		option.Ref = defaultNotesRef
+	}
+	if option.Remote == "" {
+		option.Remote = defaultRemote
+	}
+}
+
+// notesPushAttempts is how often notes add --push records and pushes while
+// other builds keep pushing their records first.
+const notesPushAttempts = 5
+
+type NotesAddOptions struct {
+	Ref       string `flag:"--ref,Notes ref holding the build records"`
+	Remote    string `flag:"--remote,Remote to push and fetch the notes ref"`
+	Set       string `flag:"--set,Comma separated list of key=value metadata to record"`
+	Checksums string `flag:"--checksums,File of artifact checksums in sha256sum format to record"`
+	Push      bool   `flag:"--push,Fetch the notes ref first and push it after recording"`
+}
+
+// buildRecord is the provenance of one build of a commit. The note on a
+// commit holds a JSON array of them, oldest first.
+type buildRecord struct {
+	Recorded  time.Time         `json:"recorded"`
+	Provider  string            `json:"provider,omitempty"`
+	RunID     string            `json:"runId,omitempty"`
+	RunURL    string            `json:"runUrl,omitempty"`
+	Job       string            `json:"job,omitempty"`
+	Actor     string            `json:"actor,omitempty"`
+	Checksums map[string]string `json:"checksums,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// executeNotesAdd appends a build record to the note on a commit, HEAD by
+// default.
+func executeNotesAdd(ctx context.Context, option *NotesAddOptions, args []string) error {
+	repo := RepositoryFromContext(ctx)
+	if option.Ref == "" {
+		option.Ref = defaultNotesRef
+	}
+	if option.Remote == "" {
+		option.Remote = defaultRemote
+	}
+	commit := "HEAD"
+	if len(args) > 0 {
+		commit = args[0]
+	}
+
+	record := newBuildRecord(detectBuild(ctx), time.Now().UTC())
+	for _, pair := range splitList(option.Set) {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || key == "" {
+			return fmt.Errorf("invalid --set value %q, expected key=value", pair)
+		}
+		if record.Metadata == nil {
+			record.Metadata = map[string]string{}
+		}
+		record.Metadata[key] = value
+	}
+	if option.Checksums != "" {
+		checksums, err := readChecksumFile(option.Checksums)
+		if err != nil {
+			return err
+		}
+		record.Checksums = checksums
+	}
+
+	if !option.Push {
+		if err := addBuildRecord(ctx, repo, option.Ref, commit, record); err != nil {
+			return err
+		}
+		slog.Info("Build record added", "commit", commit, "ref", option.Ref)
+		return nil
+	}
+
+	if err := fetchNotes(ctx, repo, option.Remote, option.Ref, false); err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		if err := addBuildRecord(ctx, repo, option.Ref, commit, record); err != nil {
+			return err
+		}
+		slog.Info("Build record added", "commit", commit, "ref", option.Ref)
+		err := repo.Push(ctx, option.Remote, PushOptions{}, option.Ref)
+		if err == nil {
+			slog.Info("Notes pushed", "ref", option.Ref, "remote", option.Remote)
+			return nil
+		}
+		if attempt == notesPushAttempts || !isPushRejected(err) {
+			return err
+		}
+		// another build pushed first, start again from its notes
+		slog.Info("Notes changed on the remote, recording again", "ref", option.Ref, "attempt", attempt)
+		if err := fetchNotes(ctx, repo, option.Remote, option.Ref, true); err != nil {
+			return err
+		}
+	}
+}
+
+// executeNotesShow prints the build records of a commit, HEAD by default.
+func executeNotesShow(ctx context.Context, option *NotesOptions, args []string) error {
+	option.setDefaults()
+	commit := "HEAD"
+	if len(args) > 0 {
+		commit = args[0]
+	}
+	records, err := readBuildRecords(ctx, RepositoryFromContext(ctx), option.Ref, commit)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("no build records on %s in %s", commit, option.Ref)
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func executeNotesPush(ctx context.Context, option *NotesOptions, args []string) error {
+	option.setDefaults()
+	return RepositoryFromContext(ctx).Push(ctx, option.Remote, PushOptions{}, option.Ref)
+}
+
+func executeNotesFetch(ctx context.Context, option *NotesOptions, args []string) error {
+	option.setDefaults()
+	return fetchNotes(ctx, RepositoryFromContext(ctx), option.Remote, option.Ref, false)
+}
+
+func newBuildRecord(build ci.Build, now time.Time) buildRecord {
+	record := buildRecord{
+		Recorded: now,
+		Provider: build.Provider,
+		RunID:    build.RunID,
+		RunURL:   build.RunURL,
+		Job:      build.Job,
+		Actor:    build.Actor,
+	}
+	if !build.IsCI() {
+		record.Actor = os.Getenv("USER")
+	}
+	return record
+}
+
+// fetchNotes updates the local notes ref, replacing local changes when
+// force is set. A remote without the ref yet is not an error, the first push
+// creates it.
+func fetchNotes(ctx context.Context, repo Repository, remote, notesRef string, force bool) error {
+	refspec := notesRef + ":" + notesRef
+	if force {
+		refspec = "+" + refspec
+	}
+	err := repo.Fetch(ctx, remote, FetchOptions{}, refspec)
+	if err != nil && strings.Contains(err.Error(), "couldn't find remote ref") {
+		slog.Info("Remote has no notes yet", "ref", notesRef, "remote", remote)
+		return nil
+	}
+	return err
+}
+
+func readBuildRecords(ctx context.Context, repo Repository, notesRef, commit string) ([]buildRecord, error) {
+	note, err := repo.ReadNote(ctx, notesRef, commit)
+	if err != nil || strings.TrimSpace(note) == "" {
+		return nil, err
+	}
+	var records []buildRecord
+	if err := json.Unmarshal([]byte(note), &records); err != nil {
+		return nil, fmt.Errorf("note on %s in %s is not a list of build records: %v", commit, notesRef, err)
+	}
+	return records, nil
+}
+
+func addBuildRecord(ctx context.Context, repo Repository, notesRef, commit string, record buildRecord) error {
+	records, err := readBuildRecords(ctx, repo, notesRef, commit)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(append(records, record), "", "  ")
+	if err != nil {
+		return err
+	}
+	return repo.WriteNote(ctx, notesRef, commit, string(data)+"\n")
+}
+
+// readChecksumFile reads "checksum  file" lines as written by sha256sum and
+// the archive checksum command.
+func readChecksumFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %v", err)
+	}
+	checksums := map[string]string{}
+	for _, line := range splitLines(string(data)) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		checksum, file, found := strings.Cut(line, " ")
+		if !found {
+			return nil, fmt.Errorf("invalid line in %s: %q", path, line)
+		}
+		// binary mode files are marked with a leading *
+		checksums[strings.TrimPrefix(strings.TrimSpace(file), "*")] = checksum
+	}
+	return checksums, nil
+}
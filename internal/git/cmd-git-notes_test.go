@@ -0,0 +1,102 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/ci"
+)
+
+func TestBuildRecords(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: initial commit")
+	ctx := WithRepository(context.Background(), repo)
+
+	checksums := filepath.Join(t.TempDir(), "SHA256SUMS")
+	if err := os.WriteFile(checksums, []byte("abc123  app-linux.tar.gz\ndef456 *app-windows.zip\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	option := &NotesAddOptions{Ref: "refs/notes/cicd", Remote: "origin", Set: "image=app:1.0", Checksums: checksums, Push: true}
+	if err := executeNotesAdd(ctx, option, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	build := ci.Build{Provider: "github", RunID: "42", Actor: "octocat"}
+	if err := addBuildRecord(ctx, repo, "refs/notes/cicd", "HEAD", newBuildRecord(build, time.Now())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := readBuildRecords(ctx, repo, "refs/notes/cicd", "HEAD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+	if records[0].Metadata["image"] != "app:1.0" || records[0].Checksums["app-windows.zip"] != "def456" {
+		t.Errorf("unexpected first record %+v", records[0])
+	}
+	if records[1].Provider != "github" || records[1].Actor != "octocat" {
+		t.Errorf("unexpected second record %+v", records[1])
+	}
+	if !slices.Contains(repo.Fetched, "refs/notes/cicd:refs/notes/cicd") || !slices.Contains(repo.Pushed, "origin refs/notes/cicd") {
+		t.Errorf("expected the notes ref to be fetched and pushed, got %v and %v", repo.Fetched, repo.Pushed)
+	}
+
+	repo.WriteNote(ctx, "refs/notes/cicd", "HEAD", "hand written note")
+	if _, err := readBuildRecords(ctx, repo, "refs/notes/cicd", "HEAD"); err == nil {
+		t.Errorf("expected an error for a note that is not JSON")
+	}
+}
+
+func TestNotesDefaults(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: initial commit")
+	ctx := WithRepository(context.Background(), repo)
+
+	if err := executeNotesAdd(ctx, &NotesAddOptions{Set: "image=app:1.0", Push: true}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(repo.Pushed, "origin "+defaultNotesRef) {
+		t.Errorf("expected %s to be pushed to origin, got %v", defaultNotesRef, repo.Pushed)
+	}
+	if err := executeNotesShow(ctx, &NotesOptions{}, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBuildRecordsPushRace(t *testing.T) {
+	repo := NewFakeRepository()
+	head := repo.Commit("feat: initial commit")
+	ctx := WithRepository(context.Background(), repo)
+	// another build pushes its record between our fetch and push
+	repo.RemoteNotes["refs/notes/cicd"] = map[string]string{head: `[{"recorded": "2025-01-01T00:00:00Z", "runId": "other"}]`}
+	repo.RejectPushes = 1
+
+	option := &NotesAddOptions{Ref: "refs/notes/cicd", Remote: "origin", Set: "run=ours", Push: true}
+	if err := executeNotesAdd(ctx, option, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, err := readBuildRecords(ctx, repo, "refs/notes/cicd", "HEAD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 || records[0].RunID != "other" || records[1].Metadata["run"] != "ours" {
+		t.Errorf("expected the other record then ours, got %+v", records)
+	}
+	if !slices.Equal(repo.Fetched, []string{"refs/notes/cicd:refs/notes/cicd", "+refs/notes/cicd:refs/notes/cicd"}) {
+		t.Errorf("expected a forced fetch after the rejected push, got %v", repo.Fetched)
+	}
+
+	repo.RejectPushes = notesPushAttempts
+	if err := executeNotesAdd(ctx, option, nil); err == nil {
+		t.Errorf("expected to give up after %d rejected pushes", notesPushAttempts)
+	}
+	if _, err := readBuildRecords(ctx, repo, "refs/notes/cicd", "no-such-commit"); err == nil {
+		t.Errorf("expected an error for an unknown commit")
+	}
+}
@@ -0,0 +1,305 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+	"github.com/davidjspooner/cicd-utilities/pkg/textfmt"
+)
+
+type PreflightOptions struct {
+	Checks      string `flag:"--checks,Comma separated list of checks to run (default: all)"`
+	Skip        string `flag:"--skip,Comma separated list of checks to skip"`
+	Base        string `flag:"--base,Start of the range checked for fixup commits (default: the latest tag)"`
+	Remote      string `flag:"--remote,Remote the branch must be up to date with"`
+	NoFetch     bool   `flag:"--no-fetch,Compare with the remote tracking branch without fetching it"`
+	MaxFileSize int    `flag:"--max-file-size,Largest tracked file allowed in KiB (-1 for no limit)"`
+}
+
+// preflightResult is the outcome of one check.
+type preflightResult struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+type preflightCheck struct {
+	Name string
+	Run  func(ctx context.Context, repo Repository, option *PreflightOptions) preflightResult
+}
+
+// preflightChecks are run in this order. Checks return a failed result
+// rather than an error so that one broken check does not hide the others.
+var preflightChecks = []preflightCheck{
+	{"clean", checkClean},
+	{"untracked", checkUntracked},
+	{"up-to-date", checkUpToDate},
+	{"submodules", checkSubmodules},
+	{"fixup-commits", checkFixupCommits},
+	{"conflict-markers", checkConflictMarkers},
+	{"large-files", checkLargeFiles},
+}
+
+// executePreflight runs the hygiene checks that should pass before tagging
+// a release and fails if any of them does not.
+func executePreflight(ctx context.Context, option *PreflightOptions, args []string) error {
+	if option.Remote == "" {
+		option.Remote = defaultRemote
+	}
+	if option.MaxFileSize == 0 {
+		option.MaxFileSize = 5120
+	}
+	results, err := runPreflight(ctx, RepositoryFromContext(ctx), option)
+	if err != nil {
+		return err
+	}
+
+	table := textfmt.NewTable(
+		&textfmt.WrapSpec{MinWidth: 16, Align: textfmt.Left, PadChar: ' '},
+		&textfmt.WrapSpec{MinWidth: 6, Align: textfmt.Left, PadChar: ' '},
+		&textfmt.WrapSpec{MaxWidth: 70, Align: textfmt.Left, PadChar: ' '},
+	)
+	table.AddRow("Check", "Result", "Detail")
+	failed := 0
+	for _, result := range results {
+		status := "pass"
+		if !result.Passed {
+			status = "FAIL"
+			failed++
+		}
+		table.AddRow(result.Name, status, result.Detail)
+	}
+	if err := table.RenderTo(os.Stdout); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d preflight checks failed", failed, len(results))
+	}
+	return nil
+}
+
+func runPreflight(ctx context.Context, repo Repository, option *PreflightOptions) ([]preflightResult, error) {
+	selected := splitList(option.Checks)
+	skipped := splitList(option.Skip)
+	for _, name := range append(slices.Clone(selected), skipped...) {
+		if !slices.ContainsFunc(preflightChecks, func(c preflightCheck) bool { return c.Name == name }) {
+			return nil, fmt.Errorf("unknown preflight check %q", name)
+		}
+	}
+
+	var results []preflightResult
+	for _, check := range preflightChecks {
+		if (len(selected) > 0 && !slices.Contains(selected, check.Name)) || slices.Contains(skipped, check.Name) {
+			continue
+		}
+		result := check.Run(ctx, repo, option)
+		result.Name = check.Name
+		slog.Debug("Preflight check", "check", check.Name, "passed", result.Passed, "detail", result.Detail)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func checkClean(ctx context.Context, repo Repository, option *PreflightOptions) preflightResult {
+	status, err := repo.Status(ctx)
+	if err != nil {
+		return preflightResult{Detail: err.Error()}
+	}
+	var changed []string
+	for _, entry := range status {
+		if !entry.IsUntracked() {
+			changed = append(changed, entry.Path)
+		}
+	}
+	if len(changed) > 0 {
+		return preflightResult{Detail: "uncommitted changes in " + summarizeList(changed)}
+	}
+	return preflightResult{Passed: true}
+}
+
+func checkUntracked(ctx context.Context, repo Repository, option *PreflightOptions) preflightResult {
+	status, err := repo.Status(ctx)
+	if err != nil {
+		return preflightResult{Detail: err.Error()}
+	}
+	var untracked []string
+	for _, entry := range status {
+		if entry.IsUntracked() {
+			untracked = append(untracked, entry.Path)
+		}
+	}
+	if len(untracked) > 0 {
+		return preflightResult{Detail: "untracked files, commit them or add them to .gitignore: " + summarizeList(untracked)}
+	}
+	return preflightResult{Passed: true}
+}
+
+func checkUpToDate(ctx context.Context, repo Repository, option *PreflightOptions) preflightResult {
+	branch, err := repo.CurrentBranch(ctx)
+	if err != nil {
+		return preflightResult{Detail: err.Error()}
+	}
+	if branch == "HEAD" {
+		return preflightResult{Passed: true, Detail: "detached HEAD, skipped"}
+	}
+	remoteBranch := option.Remote + "/" + branch
+	if !option.NoFetch {
+		refspec := fmt.Sprintf("+refs/heads/%s:refs/remotes/%s", branch, remoteBranch)
+		if err := repo.Fetch(ctx, option.Remote, FetchOptions{}, refspec); err != nil {
+			return preflightResult{Detail: err.Error()}
+		}
+	}
+	behind, err := repo.IsAncestor(ctx, remoteBranch, "HEAD")
+	if err != nil {
+		return preflightResult{Detail: err.Error()}
+	}
+	if !behind {
+		return preflightResult{Detail: fmt.Sprintf("%s has commits that are not in HEAD, pull first", remoteBranch)}
+	}
+	pushed, err := repo.IsAncestor(ctx, "HEAD", remoteBranch)
+	if err != nil {
+		return preflightResult{Detail: err.Error()}
+	}
+	if !pushed {
+		return preflightResult{Detail: fmt.Sprintf("HEAD has commits that are not on %s, push first", remoteBranch)}
+	}
+	return preflightResult{Passed: true, Detail: "same as " + remoteBranch}
+}
+
+func checkSubmodules(ctx context.Context, repo Repository, option *PreflightOptions) preflightResult {
+	submodules, err := repo.Submodules(ctx)
+	if err != nil {
+		return preflightResult{Detail: err.Error()}
+	}
+	var problems []string
+	for _, submodule := range submodules {
+		switch {
+		case !submodule.Initialized:
+			problems = append(problems, submodule.Path+" is not initialized")
+		case submodule.Conflict:
+			problems = append(problems, submodule.Path+" has merge conflicts")
+		case submodule.OutOfDate:
+			problems = append(problems, submodule.Path+" is not at the recorded commit")
+		}
+	}
+	if len(problems) > 0 {
+		return preflightResult{Detail: summarizeList(problems) + ", run git submodule update --init --recursive"}
+	}
+	return preflightResult{Passed: true, Detail: fmt.Sprintf("%d submodules", len(submodules))}
+}
+
+func checkFixupCommits(ctx context.Context, repo Repository, option *PreflightOptions) preflightResult {
+	revRange := "HEAD"
+	if option.Base != "" {
+		revRange = option.Base + "..HEAD"
+	} else if tag, err := GetLatestTag(ctx, semantic.SemVer, "HEAD", ""); err == nil {
+		revRange = tag + "..HEAD"
+	}
+	commits, err := repo.Log(ctx, LogOptions{Range: revRange})
+	if err != nil {
+		return preflightResult{Detail: err.Error()}
+	}
+	var fixups []string
+	for _, commit := range commits {
+		subject := commit.Subject()
+		if strings.HasPrefix(subject, "fixup! ") || strings.HasPrefix(subject, "squash! ") || strings.HasPrefix(subject, "amend! ") {
+			fixups = append(fixups, commit.ShortHash)
+		}
+	}
+	if len(fixups) > 0 {
+		return preflightResult{Detail: fmt.Sprintf("autosquash commits in %s: %s", revRange, summarizeList(fixups))}
+	}
+	return preflightResult{Passed: true, Detail: fmt.Sprintf("%d commits in %s", len(commits), revRange)}
+}
+
+var conflictMarkers = [][]byte{[]byte("<<<<<<< "), []byte(">>>>>>> "), []byte("<<<<<<<\n"), []byte(">>>>>>>\n")}
+
+func checkConflictMarkers(ctx context.Context, repo Repository, option *PreflightOptions) preflightResult {
+	root, files, err := trackedFiles(ctx, repo)
+	if err != nil {
+		return preflightResult{Detail: err.Error()}
+	}
+	var found []string
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			// deleted in the work tree or binary
+			continue
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(nil, len(data)+1)
+		for line := 1; scanner.Scan(); line++ {
+			text := append(scanner.Bytes(), '\n')
+			if slices.ContainsFunc(conflictMarkers, func(marker []byte) bool { return bytes.HasPrefix(text, marker) }) {
+				found = append(found, fmt.Sprintf("%s:%d", file, line))
+				break
+			}
+		}
+	}
+	if len(found) > 0 {
+		return preflightResult{Detail: "conflict markers in " + summarizeList(found)}
+	}
+	return preflightResult{Passed: true}
+}
+
+func checkLargeFiles(ctx context.Context, repo Repository, option *PreflightOptions) preflightResult {
+	if option.MaxFileSize <= 0 {
+		return preflightResult{Passed: true, Detail: "no size limit"}
+	}
+	root, files, err := trackedFiles(ctx, repo)
+	if err != nil {
+		return preflightResult{Detail: err.Error()}
+	}
+	var large []string
+	for _, file := range files {
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if size := info.Size() / 1024; size > int64(option.MaxFileSize) {
+			large = append(large, fmt.Sprintf("%s (%d KiB)", file, size))
+		}
+	}
+	if len(large) > 0 {
+		return preflightResult{Detail: fmt.Sprintf("files over %d KiB: %s", option.MaxFileSize, summarizeList(large))}
+	}
+	return preflightResult{Passed: true}
+}
+
+// trackedFiles returns the top level directory of the work tree and the
+// files git tracks in it.
+func trackedFiles(ctx context.Context, repo Repository) (string, []string, error) {
+	root, err := repo.Run(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", nil, err
+	}
+	// the whole tree with paths from the top, wherever we are run from
+	out, err := repo.Run(ctx, "ls-files", "--full-name", "-z", ":/")
+	if err != nil {
+		return "", nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return root, files, nil
+}
+
+// summarizeList joins the first few items of a list for a table cell.
+func summarizeList(items []string) string {
+	const shown = 5
+	if len(items) <= shown {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:shown], ", "), len(items)-shown)
+}
@@ -0,0 +1,70 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	root := t.TempDir()
+	files := map[string][]byte{
+		"cmd/app/main.go": []byte("package main\n<<<<<<< HEAD\n"),
+		"README.md":       []byte("Title\n=======\n"),
+		"big.bin":         make([]byte, 3*1024),
+	}
+	if err := os.MkdirAll(filepath.Join(root, "cmd", "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := NewFakeRepository()
+	repo.Commit("feat: initial commit")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("fix: handle empty input")
+	repo.Commit("fixup! fix: handle empty input")
+	repo.Dirty = []StatusEntry{{"??", "dist/app"}}
+	repo.SubmoduleList = []Submodule{{Path: "vendor/lib", Initialized: true}, {Path: "vendor/proto"}}
+	repo.Responses["rev-parse --show-toplevel"] = root
+	repo.Responses["ls-files --full-name -z :/"] = "cmd/app/main.go\x00README.md\x00big.bin\x00"
+	ctx := WithRepository(context.Background(), repo)
+
+	results, err := runPreflight(ctx, repo, &PreflightOptions{Remote: "origin", MaxFileSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"clean":            "",
+		"untracked":        "dist/app",
+		"up-to-date":       "",
+		"submodules":       "vendor/proto is not initialized",
+		"fixup-commits":    repo.commits[repo.branches["main"]].ShortHash,
+		"conflict-markers": "cmd/app/main.go:2",
+		"large-files":      "big.bin (3 KiB)",
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %+v", len(expected), results)
+	}
+	for _, result := range results {
+		detail := expected[result.Name]
+		if result.Passed != (detail == "") || !strings.Contains(result.Detail, detail) {
+			t.Errorf("%s: unexpected result %+v", result.Name, result)
+		}
+	}
+
+	results, err = runPreflight(ctx, repo, &PreflightOptions{Checks: "clean,untracked", Skip: "untracked"})
+	if err != nil || len(results) != 1 || results[0].Name != "clean" {
+		t.Errorf("expected only the clean check, got %+v %v", results, err)
+	}
+	if _, err := runPreflight(ctx, repo, &PreflightOptions{Checks: "lint"}); err == nil {
+		t.Errorf("expected an error for an unknown check")
+	}
+}
@@ -0,0 +1,127 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+type ReleaseBranchOptions struct {
+	Prefix string `flag:"--prefix,Prefix of release branch names"`
+	Remote string `flag:"--remote,Remote to push the branch to"`
+	Push   bool   `flag:"--push,Push the new branch"`
+}
+
+// setDefaults fills in the options left empty on the command line.
+func (option *ReleaseBranchOptions) setDefaults() {
+	if option.Prefix == "" {
+		option.Prefix = defaultReleaseBranchPrefix
+	}
+	if option.Remote == "" {
+		option.Remote = defaultRemote
+	}
+}
+
+// executeReleaseBranchCreate branches release/X.Y from a tag, by default
+// the latest version tag on HEAD.
+func executeReleaseBranchCreate(ctx context.Context, option *ReleaseBranchOptions, args []string) error {
+	repo := RepositoryFromContext(ctx)
+	option.setDefaults()
+
+	var tag string
+	if len(args) > 0 {
+		tag = args[0]
+	} else {
+		var err error
+		if tag, err = GetLatestTag(ctx, semantic.SemVer, "HEAD", ""); err != nil {
+			return err
+		}
+	}
+	_, _, version, err := semantic.ExtractVersionFromTag(tag)
+	if err != nil {
+		return err
+	}
+
+	name := releaseBranchName(option.Prefix, version)
+	branches, err := repo.Branches(ctx)
+	if err != nil {
+		return err
+	}
+	for _, branch := range branches {
+		if branch == name || branch == option.Remote+"/"+name {
+			return fmt.Errorf("release branch %s already exists", branch)
+		}
+	}
+
+	if err := repo.CreateBranch(ctx, name, tag); err != nil {
+		return err
+	}
+	slog.Info("Release branch created", "branch", name, "tag", tag)
+	if option.Push {
+		if err := repo.Push(ctx, option.Remote, PushOptions{}, name); err != nil {
+			return err
+		}
+		slog.Info("Release branch pushed", "branch", name, "remote", option.Remote)
+	}
+	fmt.Println(name)
+	return nil
+}
+
+type releaseBranch struct {
+	Name      string
+	Ref       string
+	Line      semantic.Version
+	LatestTag string
+}
+
+// executeReleaseBranchList prints the local and remote release branches,
+// newest version line first, with the latest tag on each.
+func executeReleaseBranchList(ctx context.Context, option *ReleaseBranchOptions, args []string) error {
+	option.setDefaults()
+	branches, err := listReleaseBranches(ctx, RepositoryFromContext(ctx), option.Prefix, option.Remote)
+	if err != nil {
+		return err
+	}
+	for _, branch := range branches {
+		latestTag := branch.LatestTag
+		if latestTag == "" {
+			latestTag = "-"
+		}
+		fmt.Printf("%-20s %-30s %s\n", branch.Name, branch.Ref, latestTag)
+	}
+	return nil
+}
+
+func listReleaseBranches(ctx context.Context, repo Repository, prefix, remote string) ([]releaseBranch, error) {
+	names, err := repo.Branches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]*releaseBranch{}
+	for _, ref := range names {
+		// prefer the local branch, fall back to the remote tracking one
+		name, isRemote := strings.CutPrefix(ref, remote+"/")
+		line, ok := parseReleaseBranch(prefix, name)
+		if !ok {
+			continue
+		}
+		if existing, ok := found[name]; ok && (isRemote || existing.Ref == name) {
+			continue
+		}
+		found[name] = &releaseBranch{Name: name, Ref: ref, Line: line}
+	}
+
+	var branches []releaseBranch
+	for _, branch := range found {
+		branch.LatestTag, _ = GetLatestTag(ctx, semantic.SemVer, branch.Ref, "")
+		branches = append(branches, *branch)
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].Line.Compare(branches[j].Line) > 0
+	})
+	return branches, nil
+}
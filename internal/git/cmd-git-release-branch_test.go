@@ -0,0 +1,126 @@
+package git
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReleaseBranchCreate(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: initial commit")
+	if err := repo.Tag("v1.4.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("feat: next feature")
+	ctx := WithRepository(context.Background(), repo)
+	option := &ReleaseBranchOptions{Prefix: "release/", Remote: "origin", Push: true}
+
+	if err := executeReleaseBranchCreate(ctx, option, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	branches, _ := repo.Branches(ctx)
+	if !slices.Contains(branches, "release/1.4") {
+		t.Errorf("expected release/1.4 to be created, got %v", branches)
+	}
+	if !slices.Contains(repo.Pushed, "origin release/1.4") {
+		t.Errorf("expected release/1.4 to be pushed, got %v", repo.Pushed)
+	}
+	if err := executeReleaseBranchCreate(ctx, option, []string{"v1.4.0"}); err == nil {
+		t.Errorf("expected an error creating release/1.4 twice")
+	}
+
+	repo.CreateBranch(ctx, "origin/release/1.4", "v1.4.0")
+	repo.CreateBranch(ctx, "origin/release/1.2", "v1.4.0")
+	list, err := listReleaseBranches(ctx, repo, "release/", "origin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, branch := range list {
+		names = append(names, branch.Name+"="+branch.LatestTag)
+	}
+	if strings.Join(names, " ") != "release/1.4=v1.4.0 release/1.2=v1.4.0" {
+		t.Errorf("unexpected release branches %v", names)
+	}
+}
+
+func TestBumpGitTagReleaseBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		message  string
+		expected string
+	}{
+		{"fix", "refuse", "fix: handle empty input", "v1.4.1"},
+		{"refused", "refuse", "feat: add --json", ""},
+		{"mapped", "patch", "feat: add --json", "v1.4.1"},
+		{"breaking", "patch", "breaking: drop --xml", "v1.4.1"},
+	}
+	for _, tc := range tests {
+		repo := NewFakeRepository()
+		repo.Commit("feat: initial commit")
+		if err := repo.Tag("v1.4.0"); err != nil {
+			t.Fatal(err)
+		}
+		repo.Checkout("release/1.4")
+		repo.Commit(tc.message)
+		ctx := WithRepository(context.Background(), repo)
+
+		err := executeBumpGitTag(ctx, &BumpGitTagOptions{
+			Prefix:              "v",
+			Remote:              "origin",
+			ReleaseBranchPrefix: "release/",
+			ReleaseBranchPolicy: tc.policy,
+		}, nil)
+		if tc.expected == "" {
+			if err == nil {
+				t.Errorf("%s: expected the bump to be refused, got %v", tc.name, repo.TagNames())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !slices.Contains(repo.TagNames(), tc.expected) {
+			t.Errorf("%s: expected tag %s, got %v", tc.name, tc.expected, repo.TagNames())
+		}
+	}
+}
+
+func TestBumpGitTagReleaseBranchDetached(t *testing.T) {
+	newRepo := func() *FakeRepository {
+		repo := NewFakeRepository()
+		repo.Commit("feat: initial commit")
+		if err := repo.Tag("v1.4.0"); err != nil {
+			t.Fatal(err)
+		}
+		repo.Checkout("release/1.4")
+		repo.Commit("feat: add --json")
+		repo.Checkout("HEAD")
+		return repo
+	}
+	option := &BumpGitTagOptions{Prefix: "v", Remote: "origin", NoFetch: true, ReleaseBranchPrefix: "release/"}
+
+	// the branch comes from CI
+	github := map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_REF": "refs/heads/release/1.4"}
+	repo := newRepo()
+	ctx := WithGetenv(WithRepository(context.Background(), repo), func(key string) string { return github[key] })
+	err := executeBumpGitTag(ctx, option, nil)
+	if err == nil || !strings.Contains(err.Error(), "release/1.4 only gets patch releases") {
+		t.Errorf("expected the policy to apply to the CI branch, got %v", err)
+	}
+
+	// no branch at all
+	repo = newRepo()
+	ctx = WithGetenv(WithRepository(context.Background(), repo), func(string) string { return "" })
+	err = executeBumpGitTag(ctx, option, nil)
+	if err == nil || !strings.Contains(err.Error(), "HEAD is detached") {
+		t.Errorf("expected a detached HEAD to fail, got %v", err)
+	}
+	if len(repo.TagNames()) != 1 {
+		t.Errorf("expected no new tag, got %v", repo.TagNames())
+	}
+}
@@ -0,0 +1,273 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+	"github.com/davidjspooner/cicd-utilities/pkg/textfmt"
+)
+
+type StatsOptions struct {
+	Format string `flag:"--format,Output format (table|markdown|json)"`
+}
+
+type releaseStats struct {
+	Range        string         `json:"range"`
+	Commits      int            `json:"commits"`
+	Types        []typeCount    `json:"types"`
+	Contributors []*contributor `json:"contributors"`
+	FilesChanged int            `json:"filesChanged"`
+	Insertions   int            `json:"insertions"`
+	Deletions    int            `json:"deletions"`
+}
+
+type typeCount struct {
+	Type    string `json:"type"`
+	Commits int    `json:"commits"`
+}
+
+type contributor struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Commits int    `json:"commits"`
+	// CoAuthored counts the commits credited with a Co-authored-by trailer.
+	CoAuthored int  `json:"coAuthored"`
+	FirstTime  bool `json:"firstTime"`
+}
+
+// defaultStatsBase returns the latest tag, or the one before it when HEAD
+// is tagged, so that the report written right after tagging covers the new
+// release. It is empty when there is no such tag.
+func defaultStatsBase(ctx context.Context, repo Repository) string {
+	tag, err := GetLatestTag(ctx, semantic.SemVer, "HEAD", "")
+	if err != nil {
+		return ""
+	}
+	if tagged, err := repo.IsAncestor(ctx, "HEAD", tag); err != nil || !tagged {
+		return tag
+	}
+	previous, err := GetLatestTag(ctx, semantic.SemVer, "HEAD^", "")
+	if err != nil {
+		return ""
+	}
+	return previous
+}
+
+// executeStats reports on the commits in a range, by default from
+// defaultStatsBase to HEAD.
+func executeStats(ctx context.Context, option *StatsOptions, args []string) error {
+	repo := RepositoryFromContext(ctx)
+
+	from, to := "", "HEAD"
+	if len(args) > 0 {
+		var found bool
+		if from, to, found = strings.Cut(args[0], ".."); !found || to == "" {
+			to = "HEAD"
+		}
+	} else {
+		from = defaultStatsBase(ctx, repo)
+	}
+
+	stats, err := getReleaseStats(ctx, repo, from, to)
+	if err != nil {
+		return err
+	}
+
+	switch option.Format {
+	case "json":
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	case "markdown":
+		fmt.Print(formatStatsMarkdown(stats))
+		return nil
+	case "table", "":
+		return renderStatsTable(stats)
+	default:
+		return fmt.Errorf("unknown format %q, expected table, markdown or json", option.Format)
+	}
+}
+
+func getReleaseStats(ctx context.Context, repo Repository, from, to string) (*releaseStats, error) {
+	stats := &releaseStats{Range: to, Types: []typeCount{}, Contributors: []*contributor{}}
+	if from != "" {
+		stats.Range = from + ".." + to
+	}
+	commits, err := repo.Log(ctx, LogOptions{Range: stats.Range, NoMerges: true})
+	if err != nil {
+		return nil, err
+	}
+	stats.Commits = len(commits)
+
+	types := map[string]int{}
+	contributors := map[string]*contributor{}
+	credit := func(name, email string) *contributor {
+		key := strings.ToLower(email)
+		if key == "" {
+			key = name
+		}
+		c, ok := contributors[key]
+		if !ok {
+			c = &contributor{Name: name, Email: email}
+			contributors[key] = c
+			stats.Contributors = append(stats.Contributors, c)
+		}
+		return c
+	}
+	for _, commit := range commits {
+		parsed, err := semantic.ParseCommit(commit.Message)
+		if err != nil {
+			types["other"]++
+		} else {
+			types[parsed.Type]++
+		}
+		credit(commit.Author, commit.AuthorEmail).Commits++
+		for _, coAuthor := range coAuthors(parsed) {
+			name, email := parseIdent(coAuthor)
+			credit(name, email).CoAuthored++
+		}
+	}
+
+	for t, n := range types {
+		stats.Types = append(stats.Types, typeCount{t, n})
+	}
+	sort.Slice(stats.Types, func(i, j int) bool {
+		if stats.Types[i].Commits != stats.Types[j].Commits {
+			return stats.Types[i].Commits > stats.Types[j].Commits
+		}
+		return stats.Types[i].Type < stats.Types[j].Type
+	})
+	sort.SliceStable(stats.Contributors, func(i, j int) bool {
+		a, b := stats.Contributors[i], stats.Contributors[j]
+		return a.Commits+a.CoAuthored > b.Commits+b.CoAuthored
+	})
+
+	// anyone without a commit before the range is a first time contributor
+	earlier := map[string]bool{}
+	if from != "" {
+		history, err := repo.Log(ctx, LogOptions{Range: from})
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range history {
+			earlier[strings.ToLower(commit.AuthorEmail)] = true
+			parsed, _ := semantic.ParseCommit(commit.Message)
+			for _, coAuthor := range coAuthors(parsed) {
+				_, email := parseIdent(coAuthor)
+				earlier[strings.ToLower(email)] = true
+			}
+		}
+	}
+	for _, c := range stats.Contributors {
+		c.FirstTime = !earlier[strings.ToLower(c.Email)]
+	}
+
+	base := from
+	if base == "" {
+		base = EmptyTree
+	}
+	shortstat, err := repo.Run(ctx, "diff", "--shortstat", base, to)
+	if err != nil {
+		return nil, err
+	}
+	stats.FilesChanged, stats.Insertions, stats.Deletions = parseShortstat(shortstat)
+	return stats, nil
+}
+
+func coAuthors(c semantic.Commit) []string {
+	var result []string
+	for _, footer := range c.Footers {
+		if strings.EqualFold(footer.Token, "Co-authored-by") {
+			result = append(result, footer.Value)
+		}
+	}
+	return result
+}
+
+// parseIdent splits "Name <email>".
+func parseIdent(ident string) (name, email string) {
+	name, email, found := strings.Cut(ident, "<")
+	if !found {
+		return strings.TrimSpace(ident), ""
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(email), ">"))
+}
+
+var shortstatFmt = regexp.MustCompile(`(\d+) (file|insertion|deletion)`)
+
+// parseShortstat reads "3 files changed, 10 insertions(+), 2 deletions(-)".
+func parseShortstat(text string) (files, insertions, deletions int) {
+	for _, match := range shortstatFmt.FindAllStringSubmatch(text, -1) {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "file":
+			files = n
+		case "insertion":
+			insertions = n
+		case "deletion":
+			deletions = n
+		}
+	}
+	return files, insertions, deletions
+}
+
+func (c *contributor) label() string {
+	label := c.Name
+	if c.Email != "" {
+		label += " <" + c.Email + ">"
+	}
+	if c.FirstTime {
+		label += " (first contribution)"
+	}
+	return label
+}
+
+func formatStatsMarkdown(stats *releaseStats) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## Statistics for %s\n\n", stats.Range)
+	fmt.Fprintf(&sb, "%d commits, %d files changed, %d insertions(+), %d deletions(-)\n\n",
+		stats.Commits, stats.FilesChanged, stats.Insertions, stats.Deletions)
+	sb.WriteString("| Type | Commits |\n|---|---:|\n")
+	for _, t := range stats.Types {
+		fmt.Fprintf(&sb, "| %s | %d |\n", t.Type, t.Commits)
+	}
+	sb.WriteString("\n| Contributor | Commits | Co-authored |\n|---|---:|---:|\n")
+	for _, c := range stats.Contributors {
+		label := c.Name
+		if c.FirstTime {
+			label += " (first contribution)"
+		}
+		fmt.Fprintf(&sb, "| %s | %d | %d |\n", strings.ReplaceAll(label, "|", "\\|"), c.Commits, c.CoAuthored)
+	}
+	return sb.String()
+}
+
+func renderStatsTable(stats *releaseStats) error {
+	table := textfmt.NewTable(
+		&textfmt.WrapSpec{MinWidth: 16, MaxWidth: 60, Align: textfmt.Left, PadChar: ' '},
+		&textfmt.WrapSpec{MinWidth: 7, Align: textfmt.Right, PadChar: ' '},
+		&textfmt.WrapSpec{MinWidth: 11, Align: textfmt.Right, PadChar: ' '},
+	)
+	fmt.Printf("Statistics for %s: %d commits, %d files changed, %d insertions(+), %d deletions(-)\n\n",
+		stats.Range, stats.Commits, stats.FilesChanged, stats.Insertions, stats.Deletions)
+	table.AddRow("Type", "Commits", "")
+	for _, t := range stats.Types {
+		table.AddRow(t.Type, strconv.Itoa(t.Commits), "")
+	}
+	table.AddBanner("")
+	table.AddRow("Contributor", "Commits", "Co-authored")
+	for _, c := range stats.Contributors {
+		table.AddRow(c.label(), strconv.Itoa(c.Commits), strconv.Itoa(c.CoAuthored))
+	}
+	return table.RenderTo(os.Stdout)
+}
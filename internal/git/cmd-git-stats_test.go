@@ -0,0 +1,75 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGetReleaseStats(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: initial commit")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("fix: handle empty input\n\nCo-authored-by: Jane Doe <jane@example.com>")
+	repo.Commit("feat: add --json")
+	repo.Commit("Update readme")
+	repo.Responses["diff --shortstat v1.0.0 HEAD"] = " 3 files changed, 10 insertions(+), 2 deletions(-)"
+	ctx := WithRepository(context.Background(), repo)
+
+	stats, err := getReleaseStats(ctx, repo, "v1.0.0", "HEAD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Commits != 3 || stats.FilesChanged != 3 || stats.Insertions != 10 || stats.Deletions != 2 {
+		t.Errorf("unexpected totals %+v", stats)
+	}
+	types := map[string]int{}
+	for _, t := range stats.Types {
+		types[t.Type] = t.Commits
+	}
+	if types["feat"] != 1 || types["fix"] != 1 || types["other"] != 1 {
+		t.Errorf("unexpected types %+v", stats.Types)
+	}
+	if len(stats.Contributors) != 2 {
+		t.Fatalf("expected 2 contributors, got %+v", stats.Contributors)
+	}
+	author, coAuthor := stats.Contributors[0], stats.Contributors[1]
+	if author.Commits != 3 || author.FirstTime {
+		t.Errorf("unexpected author %+v", author)
+	}
+	if coAuthor.Email != "jane@example.com" || coAuthor.CoAuthored != 1 || !coAuthor.FirstTime {
+		t.Errorf("unexpected co-author %+v", coAuthor)
+	}
+
+	markdown := formatStatsMarkdown(stats)
+	if !strings.Contains(markdown, "| Jane Doe (first contribution) | 0 | 1 |") {
+		t.Errorf("unexpected markdown:\n%s", markdown)
+	}
+}
+
+func TestDefaultStatsBase(t *testing.T) {
+	repo := NewFakeRepository()
+	ctx := WithRepository(context.Background(), repo)
+	repo.Commit("feat: initial commit")
+	if base := defaultStatsBase(ctx, repo); base != "" {
+		t.Errorf("expected no base without tags, got %s", base)
+	}
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if base := defaultStatsBase(ctx, repo); base != "" {
+		t.Errorf("expected no base for the first release, got %s", base)
+	}
+	repo.Commit("fix: handle empty input")
+	if base := defaultStatsBase(ctx, repo); base != "v1.0.0" {
+		t.Errorf("expected v1.0.0 before tagging, got %s", base)
+	}
+	if err := repo.Tag("v1.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if base := defaultStatsBase(ctx, repo); base != "v1.0.0" {
+		t.Errorf("expected v1.0.0 once HEAD is tagged, got %s", base)
+	}
+}
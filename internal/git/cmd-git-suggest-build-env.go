@@ -2,57 +2,276 @@ package git
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/ci"
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
 )
 
 type GetGitEnvOptions struct {
+	Format string `flag:"--format,Output format (dotenv|shell|json|make|github), github appends to $GITHUB_ENV and $GITHUB_OUTPUT"`
+	Prefix string `flag:"--prefix,Prefix of the variable names"`
+}
+
+type envVar struct {
+	Name  string
+	Value string
 }
 
 func executeGetGitEnv(ctx context.Context, options *GetGitEnvOptions, args []string) error {
-	// Get the current branch
-	currentBranch, err := GetCurrentBranch()
+	env, err := getBuildEnv(ctx, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	prefix := options.Prefix
+	if prefix == "" {
+		prefix = defaultEnvPrefix
+	}
+	prefixBuildEnv(env, prefix)
+	if options.Format == "github" {
+		return writeGitHubEnv(env)
+	}
+	out, err := formatEnv(options.Format, env)
 	if err != nil {
-		return fmt.Errorf("failed to get current branch: %v", err)
+		return err
 	}
-	fmt.Printf("BUILD_BRANCH=%s\n", currentBranch)
-	fmt.Printf("BUILD_VERSION=%s\n", suggestBuildName())
-	fmt.Printf("BUILD_CONTEXT=%s\n", getBuildContext())
-	now := time.Now().UTC()
-	fmt.Printf("BUILD_TIME=%s\n", now.Format(time.RFC1123))
+	fmt.Print(out)
 	return nil
 }
 
-func suggestBuildName() string {
+// prefixBuildEnv adds prefix to the names of the build variables, except
+// SOURCE_DATE_EPOCH which tools look for by that name.
+func prefixBuildEnv(env []envVar, prefix string) {
+	for i := range env {
+		if env[i].Name != "SOURCE_DATE_EPOCH" {
+			env[i].Name = prefix + env[i].Name
+		}
+	}
+}
+
+// getBuildEnv returns the build variables without the name prefix.
+// SOURCE_DATE_EPOCH keeps its well known name.
+func getBuildEnv(ctx context.Context, now time.Time) ([]envVar, error) {
+	repo := RepositoryFromContext(ctx)
+
+	// Get the current branch
+	currentBranch, err := repo.CurrentBranch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current branch: %v", err)
+	}
+	build := detectBuild(ctx)
+	if currentBranch == "HEAD" && build.Branch != "" {
+		// CI systems usually check out a detached HEAD
+		currentBranch = build.Branch
+	}
+	head, err := repo.Log(ctx, LogOptions{Range: "HEAD", MaxCount: 1})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %v", err)
+	}
+	if len(head) == 0 {
+		return nil, fmt.Errorf("repository has no commits")
+	}
+	status, err := repo.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dirty := len(status) > 0
+
+	describe, err := repo.Describe(ctx, "HEAD", DescribeOptions{})
+	if err != nil {
+		// like git describe --always
+		describe = head[0].ShortHash
+	}
+	if dirty {
+		describe += "-dirty"
+	}
+
+	env := []envVar{
+		{"BRANCH", currentBranch},
+		{"VERSION", suggestBuildName(ctx, repo)},
+		{"CONTEXT", getBuildContext(build)},
+		{"TIME", now.Format(time.RFC1123)},
+		{"SHA", head[0].Hash},
+		{"SHORT_SHA", head[0].ShortHash},
+		{"COMMIT_TIME", head[0].Date.UTC().Format(time.RFC3339)},
+		{"DESCRIBE", describe},
+		{"DIRTY", strconv.FormatBool(dirty)},
+		{"CI_PROVIDER", build.Provider},
+		{"RUN_URL", build.RunURL},
+		{"JOB", build.Job},
+		{"PULL_REQUEST", build.PullRequest},
+		{"IS_TAG", strconv.FormatBool(build.IsTag())},
+	}
+	// tags are looked up from HEAD, the CI branch may not exist locally
+	versions, err := getVersionEnv(ctx, repo, head[0], dirty)
+	if err != nil {
+		return nil, err
+	}
+	env = append(env, versions...)
+	env = append(env, getSubmoduleEnv(ctx, repo)...)
+	return append(env, envVar{"SOURCE_DATE_EPOCH", strconv.FormatInt(head[0].Date.Unix(), 10)}), nil
+}
+
+// getVersionEnv returns the latest release tag, the semantic version the
+// next update-tag would create and a snapshot version for builds between
+// releases such as 1.3.0-dev.4+gabcdef0.
+func getVersionEnv(ctx context.Context, repo Repository, head Commit, dirty bool) ([]envVar, error) {
+	build := "g" + head.ShortHash
+	if dirty {
+		build += ".dirty"
+	}
+
+	latestTag, err := GetLatestTag(ctx, semantic.SemVer, "HEAD", "")
+	if err != nil && !errors.Is(err, errNoVersionTag) {
+		return nil, fmt.Errorf("failed to get the latest tag: %v", err)
+	}
+	if err != nil {
+		// nothing released yet
+		commits, err := repo.Log(ctx, LogOptions{Range: "HEAD"})
+		if err != nil {
+			return nil, err
+		}
+		return []envVar{
+			{"LATEST_TAG", ""},
+			{"NEXT_VERSION", ""},
+			{"SNAPSHOT_VERSION", fmt.Sprintf("0.0.0-dev.%d+%s", len(commits), build)},
+		}, nil
+	}
+
+	plan, err := planNextTag(ctx, semantic.SemVer, "HEAD", "", "", nil, false, versionStrategy{Quiet: true})
+	if err != nil {
+		return nil, err
+	}
+	next := plan.Current
+	snapshot := next.String()
+	if plan.NewVersion != nil {
+		next = plan.NewVersion
+		snapshot = fmt.Sprintf("%s-dev.%d", next.String(), len(plan.Commits))
+	}
+	if len(plan.Commits) > 0 || dirty {
+		snapshot += "+" + build
+	}
+	return []envVar{
+		{"LATEST_TAG", latestTag},
+		{"NEXT_VERSION", next.String()},
+		{"SNAPSHOT_VERSION", snapshot},
+	}, nil
+}
+
+// getSubmoduleEnv reports the commit of each submodule as path=sha and the
+// submodules with uncommitted changes. Repositories without submodules get
+// no variables, nor do those whose submodules cannot be listed.
+func getSubmoduleEnv(ctx context.Context, repo Repository) []envVar {
+	submodules, err := repo.Submodules(ctx)
+	if err != nil {
+		slog.Warn("Cannot list submodules", "error", err)
+		return nil
+	}
+	if len(submodules) == 0 {
+		return nil
+	}
+	var commits, dirty []string
+	for _, submodule := range submodules {
+		commits = append(commits, submodule.Path+"="+submodule.Commit)
+		if submodule.Dirty {
+			dirty = append(dirty, submodule.Path)
+		}
+	}
+	return []envVar{
+		{"SUBMODULES", strings.Join(commits, ",")},
+		{"SUBMODULES_DIRTY", strings.Join(dirty, ",")},
+	}
+}
+
+func suggestBuildName(ctx context.Context, repo Repository) string {
+	head, err := repo.Log(ctx, LogOptions{Range: "HEAD", MaxCount: 1})
+	if err != nil || len(head) == 0 {
+		return "UNKNOWN"
+	}
+
 	// Check for uncommitted changes
-	out, err := Run("status", "--porcelain")
+	status, err := repo.Status(ctx)
 	if err != nil {
 		return "UNKNOWN"
 	}
-	if strings.TrimSpace(out) != "" {
-		return "HEAD." + time.Now().Format("060102.1504")
+	if len(status) > 0 {
+		hash, err := dirtyHash(ctx, repo, status)
+		if err != nil {
+			return "UNKNOWN"
+		}
+		return head[0].ShortHash + ".dirty." + hash
 	}
 
 	// Check for a tag version
-	tag, err := Run("tag", "--contains", "HEAD")
+	tag, err := repo.Describe(ctx, "HEAD", DescribeOptions{ExactMatch: true})
 	if err == nil && tag != "" {
 		return tag
 	}
 
 	// Fallback to short commit hash
-	commitHash, err := Run("rev-parse", "--short", "HEAD")
+	return head[0].ShortHash
+}
+
+// dirtyHash returns a short hash of the uncommitted changes: the diff of
+// tracked files against HEAD and the names and contents of untracked files
+// that are not ignored.
+// The same dirty tree always gives the same hash.
+func dirtyHash(ctx context.Context, repo Repository, status []StatusEntry) (string, error) {
+	h := sha256.New()
+	diff, err := repo.Diff(ctx, "HEAD")
 	if err != nil {
-		return "UNKNOWN"
+		return "", err
+	}
+	io.WriteString(h, diff)
+
+	if !slices.ContainsFunc(status, StatusEntry.IsUntracked) {
+		return hex.EncodeToString(h.Sum(nil))[:8], nil
+	}
+	root, err := repo.Run(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	// status lists untracked directories rather than the files in them, ask
+	// for the files themselves, leaving out the ignored ones
+	out, err := repo.Run(ctx, "ls-files", "--others", "--exclude-standard", "--full-name", "-z", ":/")
+	if err != nil {
+		return "", fmt.Errorf("failed to list untracked files: %v", err)
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			return "", fmt.Errorf("failed to read untracked files: %v", err)
+		}
+		fmt.Fprintf(h, "\x00%s\x00%d\x00", file, len(data))
+		h.Write(data)
 	}
-	return commitHash
+	return hex.EncodeToString(h.Sum(nil))[:8], nil
 }
 
-func getBuildContext() string {
-	// Check for github actions
-	if os.Getenv("GITHUB_ACTIONS") == "true" {
-		return os.Getenv("GITHUB_RUN_ID")
+// getBuildContext identifies where the build ran: the CI run ID, or
+// user@hostname for local builds.
+func getBuildContext(build ci.Build) string {
+	if build.IsCI() {
+		return build.RunID
 	}
 
 	// Fallback to user@hostname
@@ -0,0 +1,217 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetBuildEnv(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC)
+	repo := NewFakeRepository()
+	first := repo.Commit("feat: first")
+	ctx := WithRepository(context.Background(), repo)
+
+	lookup := func(env []envVar, name string) string {
+		for _, v := range env {
+			if v.Name == name {
+				return v.Value
+			}
+		}
+		t.Fatalf("%s not found in %v", name, env)
+		return ""
+	}
+
+	env, err := getBuildEnv(ctx, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"VERSION":          first[:7],
+		"BRANCH":           "main",
+		"SHA":              first,
+		"SHORT_SHA":        first[:7],
+		"DESCRIBE":         first[:7],
+		"DIRTY":            "false",
+		"LATEST_TAG":       "",
+		"SNAPSHOT_VERSION": "0.0.0-dev.1+g" + first[:7],
+	}
+	for name, value := range expected {
+		if got := lookup(env, name); got != value {
+			t.Errorf("untagged: expected %s=%s, got %s", name, value, got)
+		}
+	}
+
+	if err := repo.Tag("v1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	env, _ = getBuildEnv(ctx, now)
+	expected = map[string]string{
+		"VERSION":          "v1.2.0",
+		"DESCRIBE":         "v1.2.0",
+		"LATEST_TAG":       "v1.2.0",
+		"NEXT_VERSION":     "1.2.0",
+		"SNAPSHOT_VERSION": "1.2.0",
+	}
+	for name, value := range expected {
+		if got := lookup(env, name); got != value {
+			t.Errorf("tagged: expected %s=%s, got %s", name, value, got)
+		}
+	}
+
+	repo.Commit("fix: one")
+	head := repo.Commit("feat: two")
+	repo.Dirty = []StatusEntry{{Code: " M", Path: "main.go"}}
+	env, _ = getBuildEnv(ctx, now)
+	hash, _ := dirtyHash(ctx, repo, repo.Dirty)
+	expected = map[string]string{
+		"VERSION":           head[:7] + ".dirty." + hash,
+		"DESCRIBE":          "v1.2.0-2-g" + head[:7] + "-dirty",
+		"DIRTY":             "true",
+		"NEXT_VERSION":      "1.3.0",
+		"SNAPSHOT_VERSION":  "1.3.0-dev.2+g" + head[:7] + ".dirty",
+		"COMMIT_TIME":       repo.commits[head].Date.Format(time.RFC3339),
+		"SOURCE_DATE_EPOCH": strconv.FormatInt(repo.commits[head].Date.Unix(), 10),
+	}
+	for name, value := range expected {
+		if got := lookup(env, name); got != value {
+			t.Errorf("dirty: expected %s=%s, got %s", name, value, got)
+		}
+	}
+
+	repo.SubmoduleList = []Submodule{
+		{Path: "vendor/a", Commit: "1111111", Initialized: true},
+		{Path: "vendor/b", Commit: "2222222", Initialized: true, Dirty: true},
+	}
+	env, _ = getBuildEnv(ctx, now)
+	if got := lookup(env, "SUBMODULES"); got != "vendor/a=1111111,vendor/b=2222222" {
+		t.Errorf("unexpected SUBMODULES=%s", got)
+	}
+	if got := lookup(env, "SUBMODULES_DIRTY"); got != "vendor/b" {
+		t.Errorf("unexpected SUBMODULES_DIRTY=%s", got)
+	}
+	repo.SubmodulesErr = errors.New("fake: submodule status failed")
+	env, err = getBuildEnv(ctx, now)
+	if err != nil {
+		t.Fatalf("expected a failing submodule status to be ignored, got %v", err)
+	}
+	for _, v := range env {
+		if v.Name == "SUBMODULES" {
+			t.Errorf("expected no SUBMODULES when they cannot be listed, got %s", v.Value)
+		}
+	}
+}
+
+func TestDirtyHash(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "gen"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, "gen", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.txt", "one")
+	write("build.log", "ignored")
+	repo := NewFakeRepository()
+	repo.Commit("feat: first")
+	repo.Responses["rev-parse --show-toplevel"] = root
+	repo.Responses["ls-files --others --exclude-standard --full-name -z :/"] = "gen/a.txt\x00"
+	ctx := WithRepository(context.Background(), repo)
+
+	modified := []StatusEntry{{Code: " M", Path: "main.go"}}
+	withUntracked := append(modified, StatusEntry{Code: "??", Path: "gen/"})
+	hashes := map[string]bool{}
+	for _, status := range [][]StatusEntry{modified, withUntracked} {
+		repo.Dirty = status
+		first, err := dirtyHash(ctx, repo, status)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		second, err := dirtyHash(ctx, repo, status)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if first != second || len(first) != 8 {
+			t.Errorf("expected the same 8 character hash twice, got %s and %s", first, second)
+		}
+		hashes[first] = true
+	}
+
+	before, err := dirtyHash(ctx, repo, withUntracked)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	write("build.log", "changed")
+	if after, err := dirtyHash(ctx, repo, withUntracked); err != nil || after != before {
+		t.Errorf("expected ignored files to leave the hash alone, got %s and %s (%v)", before, after, err)
+	}
+
+	write("a.txt", "two")
+	changed, err := dirtyHash(ctx, repo, withUntracked)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hashes[changed] = true
+	if len(hashes) != 3 {
+		t.Errorf("expected different hashes for different dirty trees, got %v", hashes)
+	}
+}
+
+func TestGetBuildEnvDetachedPullRequest(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: first")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	head := repo.Commit("feat: add export")
+	repo.Checkout("HEAD")
+	github := map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_HEAD_REF": "feature-x", "GITHUB_EVENT_NAME": "pull_request"}
+	ctx := WithGetenv(WithRepository(context.Background(), repo), func(key string) string { return github[key] })
+
+	env, err := getBuildEnv(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"BRANCH":           "feature-x",
+		"LATEST_TAG":       "v1.0.0",
+		"NEXT_VERSION":     "1.1.0",
+		"SNAPSHOT_VERSION": "1.1.0-dev.1+g" + head[:7],
+	}
+	for _, v := range env {
+		if value, ok := expected[v.Name]; ok && v.Value != value {
+			t.Errorf("expected %s=%s, got %s", v.Name, value, v.Value)
+		}
+	}
+}
+
+func TestGetBuildEnvQuiet(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: first")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("fix: handle empty input")
+	ctx := WithGetenv(WithRepository(context.Background(), repo), func(string) string { return "" })
+
+	// the variables are printed on stdout, where the logs go too
+	var logs strings.Builder
+	saved := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	defer slog.SetDefault(saved)
+
+	if _, err := getBuildEnv(ctx, time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no info logs, got:\n%s", logs.String())
+	}
+}
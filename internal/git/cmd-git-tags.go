@@ -0,0 +1,137 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+	"github.com/davidjspooner/cicd-utilities/pkg/textfmt"
+)
+
+type TagsOptions struct {
+	Prefix      string `flag:"--prefix,Only list tags starting with this prefix such as a component tag prefix"`
+	Constraint  string `flag:"--constraint,Only list versions in this range such as >=1.2.0 <2.0.0 or ~1.4"`
+	PreReleases bool   `flag:"--pre-releases,Include pre-release versions such as 1.2.0-rc.1"`
+	Merged      string `flag:"--merged,Only list tags reachable from this ref"`
+	Format      string `flag:"--format,Output format (table|json)"`
+}
+
+// versionTag is a tag with its parsed version and history.
+type versionTag struct {
+	Name       string    `json:"name"`
+	Version    string    `json:"version"`
+	Prerelease string    `json:"prerelease,omitempty"`
+	Commit     string    `json:"commit"`
+	CommitDate time.Time `json:"commitDate"`
+	Tagger     string    `json:"tagger"`
+	// Commits counts the commits since the previous tag in the list.
+	Commits int `json:"commits"`
+
+	version semantic.Version
+}
+
+// executeTags lists version tags in version order, oldest first.
+func executeTags(ctx context.Context, option *TagsOptions, args []string) error {
+	var constraint *semantic.Constraint
+	if option.Constraint != "" {
+		c, err := semantic.ParseConstraint(option.Constraint)
+		if err != nil {
+			return err
+		}
+		constraint = &c
+	}
+
+	tags, err := listVersionTags(ctx, RepositoryFromContext(ctx), option.Prefix, option.Merged, constraint, option.PreReleases)
+	if err != nil {
+		return err
+	}
+
+	switch option.Format {
+	case "json":
+		data, err := json.MarshalIndent(tags, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "table", "":
+		table := textfmt.NewTable(
+			&textfmt.WrapSpec{MinWidth: 12, Align: textfmt.Left, PadChar: ' '},
+			&textfmt.WrapSpec{MinWidth: 10, Align: textfmt.Left, PadChar: ' '},
+			&textfmt.WrapSpec{MinWidth: 10, Align: textfmt.Left, PadChar: ' '},
+			&textfmt.WrapSpec{MinWidth: 12, MaxWidth: 30, Align: textfmt.Left, PadChar: ' '},
+			&textfmt.WrapSpec{MinWidth: 7, Align: textfmt.Right, PadChar: ' '},
+		)
+		table.AddRow("Tag", "Version", "Date", "Tagger", "Commits")
+		for _, tag := range tags {
+			table.AddRow(tag.Name, tag.Version, tag.CommitDate.Format(time.DateOnly), tag.Tagger, strconv.Itoa(tag.Commits))
+		}
+		return table.RenderTo(os.Stdout)
+	default:
+		return fmt.Errorf("unknown format %q, expected table or json", option.Format)
+	}
+	return nil
+}
+
+// listVersionTags returns the semantic version tags sorted by version,
+// with pre-releases ordered before their release.
+func listVersionTags(ctx context.Context, repo Repository, prefix, merged string, constraint *semantic.Constraint, preReleases bool) ([]versionTag, error) {
+	tags, err := repo.Tags(ctx, merged)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []versionTag{}
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag.Name, prefix) {
+			continue
+		}
+		tagPrefix, suffix, version, err := semantic.ExtractVersionFromTag(strings.TrimPrefix(tag.Name, prefix))
+		if err != nil || (prefix != "" && tagPrefix != "") {
+			continue
+		}
+		prerelease := semantic.Prerelease(suffix)
+		if prerelease != "" && !preReleases {
+			continue
+		}
+		if constraint != nil && !constraint.Check(version) {
+			continue
+		}
+		result = append(result, versionTag{
+			Name:       tag.Name,
+			Version:    version.String(),
+			Prerelease: prerelease,
+			Commit:     tag.Commit,
+			CommitDate: tag.Date,
+			Tagger:     tag.Tagger,
+			version:    version,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if c := result[i].version.Compare(result[j].version); c != 0 {
+			return c < 0
+		}
+		return semantic.ComparePrerelease(result[i].Prerelease, result[j].Prerelease) < 0
+	})
+
+	for i := range result {
+		if result[i].Prerelease != "" {
+			result[i].Version += "-" + result[i].Prerelease
+		}
+		revRange := result[i].Commit
+		if i > 0 {
+			revRange = result[i-1].Commit + ".." + revRange
+		}
+		commits, err := repo.Log(ctx, LogOptions{Range: revRange})
+		if err != nil {
+			return nil, err
+		}
+		result[i].Commits = len(commits)
+	}
+	return result, nil
+}
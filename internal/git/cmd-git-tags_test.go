@@ -0,0 +1,66 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+func TestListVersionTags(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: initial commit")
+	if err := repo.Tag("v1.10.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("fix: one")
+	repo.Commit("fix: two")
+	if err := repo.Tag("v1.9.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("feat: three")
+	if err := repo.Tag("v2.0.0-rc.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Tag("v2.0.0-beta.2"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("fix: four")
+	if err := repo.Tag("v2.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Tag("release-notes"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithRepository(context.Background(), repo)
+
+	tags, err := listVersionTags(ctx, repo, "", "", nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []struct {
+		name    string
+		commits int
+	}{{"v1.9.0", 3}, {"v1.10.0", 0}, {"v2.0.0-beta.2", 3}, {"v2.0.0-rc.1", 0}, {"v2.0.0", 1}}
+	if len(tags) != len(expected) {
+		t.Fatalf("expected %d tags, got %+v", len(expected), tags)
+	}
+	for i, tag := range tags {
+		if tag.Name != expected[i].name || tag.Commits != expected[i].commits {
+			t.Errorf("expected %s with %d commits at %d, got %s with %d", expected[i].name, expected[i].commits, i, tag.Name, tag.Commits)
+		}
+	}
+	// v1.9.0 was tagged two commits after v1.10.0
+	if !tags[0].CommitDate.After(tags[1].CommitDate) {
+		t.Errorf("expected the commit dates of the tags, got %v and %v", tags[0].CommitDate, tags[1].CommitDate)
+	}
+
+	constraint, _ := semantic.ParseConstraint(">=1.10")
+	tags, err = listVersionTags(ctx, repo, "v", "", &constraint, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "v1.10.0" || tags[1].Name != "v2.0.0" || tags[1].Commits != 4 {
+		t.Errorf("unexpected filtered tags %+v", tags)
+	}
+}
@@ -2,135 +2,556 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+	"github.com/davidjspooner/cicd-utilities/pkg/versionfile"
 )
 
 type BumpGitTagOptions struct {
-	Prefix string `flag:"--prefix,Prefix string"`
+	Prefix string `flag:"--prefix,Prefix string (default: v with the semver scheme)"`
 	Suffix string `flag:"--suffix,Suffix string"`
-	DryRun bool   `flag:"--dry-run,Do not push the tag"`
+	DryRun bool   `flag:"--dry-run,Only log the new tags, without fetching, tagging or pushing"`
 	Remote string `flag:"--remote,Remote to push the tag to"`
+	Scheme string `flag:"--scheme,Version scheme (semver|calver)"`
+	Format string `flag:"--calver-format,CalVer format built from YYYY YY 0M MM DD and MICRO"`
+
+	ReleaseCommit bool   `flag:"--release-commit,Write the new version into the version files and commit them before tagging"`
+	VersionFiles  string `flag:"--version-files,Comma separated list of files for --release-commit (default: well known files at the top of the repository)"`
+
+	Components string `flag:"--components,JSON file of components with their own paths and tag prefix, only changed components are tagged"`
+
+	InitialVersion string `flag:"--initial-version,Version of the first release when there is no version tag yet, auto lets the scheme choose (calver)"`
+	FromTag        string `flag:"--from-tag,Tag to release from instead of the latest version tag"`
+	ForceBump      string `flag:"--force-bump,Increment to use instead of analysing the commits (major|minor|patch)"`
+
+	ReleaseBranchPrefix string `flag:"--release-branch-prefix,Branches named with this prefix and a minor version such as release/1.4 only get patch releases"`
+	ReleaseBranchPolicy string `flag:"--release-branch-policy,What to do with feature or breaking changes on a release branch (refuse|patch)"`
+
+	FirstParent   bool `flag:"--first-parent,Only follow the first parent of merges when collecting commits"`
+	NoMerges      bool `flag:"--no-merges,Ignore merge commits when working out the bump"`
+	NoReverts     bool `flag:"--no-reverts,Ignore revert commits when working out the bump"`
+	CancelReverts bool `flag:"--cancel-reverts,Ignore reverts together with the commit they revert when both are unreleased"`
+	FullMessage   bool `flag:"--full-message,Parse whole commit messages so BREAKING CHANGE footers and scopes count"`
+
+	NoFetch    bool `flag:"--no-fetch,Do not fetch the remote and check that HEAD is pushed and the new tags are free before tagging"`
+	FetchDepth int  `flag:"--fetch-depth,Maximum number of commits to add to a shallow clone while looking for the latest version tag"`
+
+	Annotate   bool   `flag:"--annotate,Create annotated tags instead of lightweight ones"`
+	Message    string `flag:"--message,Message of annotated tags (default: Release and the tag name)"`
+	Changelog  bool   `flag:"--changelog,Add the changelog of the released commits to the tag message"`
+	Sign       bool   `flag:"--sign,Sign the tags with an OpenPGP key from --signing-key or $CICD_SIGNING_KEY"`
+	SigningKey string `flag:"--signing-key,File with an armored OpenPGP private key, its passphrase is read from $CICD_SIGNING_PASSPHRASE"`
 }
 
 func executeBumpGitTag(ctx context.Context, option *BumpGitTagOptions, args []string) error {
+	if option.Remote == "" {
+		option.Remote = defaultRemote
+	}
+	if option.FetchDepth == 0 {
+		option.FetchDepth = 1000
+	}
+	if option.ReleaseBranchPrefix == "" {
+		option.ReleaseBranchPrefix = defaultReleaseBranchPrefix
+	}
+
+	scheme, err := semantic.LookupScheme(option.Scheme, option.Format)
+	if err != nil {
+		return err
+	}
+	if option.Prefix == "" && scheme == semantic.SemVer {
+		option.Prefix = "v"
+	}
+
+	strategy := versionStrategy{
+		InitialVersion:      option.InitialVersion,
+		FromTag:             option.FromTag,
+		ForceBump:           option.ForceBump,
+		ReleaseBranchPrefix: option.ReleaseBranchPrefix,
+		ReleaseBranchPolicy: option.ReleaseBranchPolicy,
+		Commits: commitSelection{
+			FirstParent:   option.FirstParent,
+			NoMerges:      option.NoMerges,
+			NoReverts:     option.NoReverts,
+			CancelReverts: option.CancelReverts,
+			FullMessage:   option.FullMessage,
+		},
+	}
+	switch strategy.ForceBump {
+	case "", "major", "minor", "patch":
+	default:
+		return fmt.Errorf("invalid --force-bump %q, expected major, minor or patch", strategy.ForceBump)
+	}
+	if strategy.FromTag != "" && option.Components != "" {
+		return fmt.Errorf("--from-tag cannot be used with --components")
+	}
+
+	repo := RepositoryFromContext(ctx)
 
 	// Get the current branch
-	currentBranch, err := GetCurrentBranch()
+	currentBranch, err := repo.CurrentBranch(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get current branch: %v", err)
 	}
 
-	// Get the latest tag
-	latestTag, err := getLatestTag(ctx, currentBranch)
-	if err != nil {
-		return fmt.Errorf("failed to get the latest tag: %v", err)
+	if strategy.ReleaseBranchPrefix != "" {
+		if strategy.ReleaseBranch, err = resolveReleaseBranch(currentBranch, detectBuild(ctx)); err != nil {
+			return fmt.Errorf("%v, check out the branch to apply the release branch policy", err)
+		}
+	}
+	// the release commit is pushed to the branch being released
+	var pushBranch string
+	if option.ReleaseCommit {
+		if pushBranch, err = resolveReleaseBranch(currentBranch, detectBuild(ctx)); err != nil {
+			return fmt.Errorf("%v, check out the branch to push the release commit", err)
+		}
 	}
 
-	_, _, currentVersion, err := semantic.ExtractVersionFromTag(latestTag)
-	if err != nil {
-		return fmt.Errorf("failed to extract version from tag: %v", err)
+	var components []Component
+	if option.Components != "" {
+		if components, err = loadComponents(option.Components, args); err != nil {
+			return err
+		}
 	}
 
-	slog.Info("Current", "tag", latestTag, "version", currentVersion.String())
+	// a dry run works offline with the history at hand
+	remoteChecks := !option.NoFetch && !option.DryRun
+	if remoteChecks {
+		if err := fetchRemoteState(ctx, repo, option.Remote, currentBranch); err != nil {
+			return err
+		}
+		err := deepenUntil(ctx, repo, option.Remote, option.FetchDepth, func() bool {
+			if strategy.FromTag != "" {
+				found, err := repo.IsAncestor(ctx, strategy.FromTag, "HEAD")
+				return err == nil && found
+			}
+			return hasVersionTags(ctx, scheme, currentBranch, components)
+		})
+		if err != nil {
+			return err
+		}
+	}
 
-	// Get commit messages since the latest tag
-	commitMessages, err := Run("log", fmt.Sprintf("%s..HEAD", latestTag), "--pretty=format:%s")
-	if err != nil {
-		return fmt.Errorf("failed to get commit messages: %v", err)
+	var plans []*tagPlan
+	if option.Components != "" {
+		for _, component := range components {
+			plan, err := planNextTag(ctx, scheme, currentBranch, component.TagPrefix, option.Suffix, component.Paths, true, strategy)
+			if errors.Is(err, errNoVersionTag) {
+				// a new component should not hold back the release of the others
+				slog.Warn("No version tag yet, use --initial-version for the first release", "component", component.Name, "prefix", component.TagPrefix)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("component %s: %v", component.Name, err)
+			}
+			plan.Component = component.Name
+			plan.VersionFiles = component.VersionFiles
+			if len(plan.VersionFiles) > 0 {
+				if plan.VersionFiles, err = repoPaths(ctx, repo, plan.VersionFiles); err != nil {
+					return err
+				}
+			}
+			plans = append(plans, plan)
+		}
+	} else {
+		plan, err := planNextTag(ctx, scheme, currentBranch, option.Prefix, option.Suffix, nil, false, strategy)
+		if err != nil {
+			return err
+		}
+		plan.VersionFiles = splitList(option.VersionFiles)
+		if option.ReleaseCommit && len(plan.VersionFiles) == 0 {
+			root, err := repo.Run(ctx, "rev-parse", "--show-toplevel")
+			if err != nil {
+				return err
+			}
+			plan.VersionFiles = versionfile.DefaultFiles(root)
+		}
+		plans = append(plans, plan)
 	}
-	commits := splitLines(commitMessages)
-	nCommits := 0
-	for i := range commits {
-		commits[i] = strings.TrimSpace(commits[i])
-		if commits[i] == "" {
+
+	var changed []*tagPlan
+	for _, plan := range plans {
+		if plan.NewTag == "" {
+			if plan.Component != "" {
+				slog.Info("No changes detected", "component", plan.Component, "tag", plan.LatestTag)
+			}
 			continue
 		}
-		nCommits++
+		if option.ReleaseCommit && len(plan.VersionFiles) == 0 {
+			return fmt.Errorf("--release-commit needs version files for %s", plan.NewTag)
+		}
+		changed = append(changed, plan)
 	}
-	if nCommits == 0 {
+	if len(changed) == 0 {
 		fmt.Println("No changes deteced, no version increment needed.")
 		return nil
 	}
+	if remoteChecks {
+		if err := checkNewTags(ctx, repo, scheme, option.Remote, currentBranch, changed); err != nil {
+			return err
+		}
+	}
 
-	// Determine the version increment
-	increment, err := semantic.Bumps.GetVersionBump(commits)
+	// Load the key before anything is changed so a bad key fails early
+	var sign func([]byte) (string, error)
+	if option.Sign {
+		entity, err := loadSigningKey(option.SigningKey)
+		if err != nil {
+			return err
+		}
+		sign = detachedSigner(entity)
+	}
+
+	if option.DryRun {
+		for _, plan := range changed {
+			slog.Info("--dry-run", "newTag", plan.NewTag, "versionFiles", plan.VersionFiles)
+		}
+		printComponentTags(option, changed)
+		return nil
+	}
+
+	pushRefs := []string{}
+	committed := false
+	if option.ReleaseCommit {
+		if committed, err = createReleaseCommit(ctx, changed); err != nil {
+			return err
+		}
+		if committed {
+			pushRefs = append(pushRefs, "HEAD:refs/heads/"+pushBranch)
+		}
+	}
+
+	// Create and push the new tags
+	var created []string
+	for _, plan := range changed {
+		if err := repo.CreateTag(ctx, plan.NewTag, "HEAD", tagOptions(option, plan, sign)); err != nil {
+			deleteTags(ctx, repo, created)
+			if committed {
+				dropReleaseCommit(ctx, repo)
+			}
+			return err
+		}
+		created = append(created, plan.NewTag)
+		pushRefs = append(pushRefs, plan.NewTag)
+	}
+	if err := pushTags(ctx, repo, option.Remote, pushRefs, created); err != nil {
+		if committed {
+			dropReleaseCommit(ctx, repo)
+		}
+		return err
+	}
+
+	for _, plan := range changed {
+		slog.Info("Tag created and pushed", "tag", plan.NewTag)
+	}
+	printComponentTags(option, changed)
+	return nil
+}
+
+// printComponentTags writes the new tags to stdout, one per line, so that
+// a pipeline can act on the components that were released.
+func printComponentTags(option *BumpGitTagOptions, plans []*tagPlan) {
+	if option.Components == "" {
+		return
+	}
+	for _, plan := range plans {
+		fmt.Println(plan.NewTag)
+	}
+}
+
+// tagOptions returns how the tag of plan is created. Signing, a message or
+// a changelog all imply an annotated tag.
+func tagOptions(option *BumpGitTagOptions, plan *tagPlan, sign func([]byte) (string, error)) TagOptions {
+	if !option.Annotate && !option.Changelog && !option.Sign && option.Message == "" {
+		return TagOptions{}
+	}
+	message := option.Message
+	if message == "" {
+		message = "Release " + plan.NewTag
+	}
+	if option.Changelog {
+		cl := buildChangelog(plan.NewTag, time.Now().UTC(), plan.LatestTag, plan.NewTag, plan.Commits)
+		message += "\n\n" + cl.Markdown("")
+	}
+	return TagOptions{Message: message, Sign: sign}
+}
+
+// tagPlan is the result of analysing the commits since the latest tag.
+// NewTag is empty when there is nothing to release.
+type tagPlan struct {
+	Component    string
+	Strategy     string
+	TagFilter    string
+	LatestTag    string
+	Current      semantic.Versioned
+	Commits      []Commit
+	Bump         string
+	NewVersion   semantic.Versioned
+	NewTag       string
+	VersionFiles []string
+}
+
+// versionStrategy overrides how planNextTag picks the base version and the
+// bump. The zero value uses the latest tag and the commit messages.
+type versionStrategy struct {
+	InitialVersion      string
+	FromTag             string
+	ForceBump           string
+	ReleaseBranchPrefix string
+	ReleaseBranchPolicy string
+	// ReleaseBranch is the branch the policy applies to, which CI names
+	// when HEAD is detached.
+	ReleaseBranch string
+	Commits       commitSelection
+	// Quiet logs the plan at debug level, for commands that print their
+	// own result on stdout.
+	Quiet bool
+}
+
+func (strategy versionStrategy) logLevel() slog.Level {
+	if strategy.Quiet {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// planNextTag works out the next tag for branch. When paths are given only
+// commits touching them count, and when filterPrefix is set only tags
+// starting with prefix are considered.
+func planNextTag(ctx context.Context, scheme semantic.Scheme, branch, prefix, suffix string, paths []string, filterPrefix bool, strategy versionStrategy) (*tagPlan, error) {
+	tagPrefix := ""
+	if filterPrefix {
+		tagPrefix = prefix
+	}
+
+	// Get the latest tag
+	base := "from-tag"
+	latestTag := strategy.FromTag
+	if latestTag == "" {
+		var err error
+		base = "latest-tag"
+		latestTag, err = GetLatestTag(ctx, scheme, branch, tagPrefix)
+		if errors.Is(err, errNoVersionTag) && strategy.InitialVersion != "" {
+			return planInitialVersion(ctx, scheme, prefix, suffix, tagPrefix, paths, strategy)
+		}
+		if errors.Is(err, errNoVersionTag) {
+			return nil, fmt.Errorf("failed to get the latest tag: %w, use --initial-version for the first release", err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the latest tag: %v", err)
+		}
+	}
+
+	_, _, currentVersion, err := scheme.ExtractVersionFromTag(latestTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract version from tag: %v", err)
+	}
+	plan := &tagPlan{Strategy: base, TagFilter: tagPrefix, LatestTag: latestTag, Current: currentVersion}
+
+	slog.Log(ctx, strategy.logLevel(), "Current", "tag", latestTag, "version", currentVersion.String(), "scheme", scheme.Name())
+
+	// Get commit messages since the latest tag
+	plan.Commits, err = RepositoryFromContext(ctx).Log(ctx, strategy.Commits.logOptions(latestTag+"..HEAD", paths))
 	if err != nil {
-		return fmt.Errorf("failed to determine version increment: %v", err)
+		return nil, fmt.Errorf("failed to get commit messages: %v", err)
+	}
+	plan.Commits = strategy.Commits.filter(plan.Commits)
+	if len(plan.Commits) == 0 {
+		return plan, nil
+	}
+
+	// Determine the version increment
+	bumpSource := "forced"
+	plan.Bump = strategy.ForceBump
+	if plan.Bump == "" {
+		bumpSource = "commits"
+		plan.Bump, err = strategy.Commits.bump(plan.Commits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine version increment: %v", err)
+		}
+	}
+
+	if scheme == semantic.SemVer {
+		if plan.Bump, err = releaseBranchBump(strategy.ReleaseBranchPrefix, strategy.ReleaseBranchPolicy, strategy.ReleaseBranch, plan.Bump); err != nil {
+			return nil, err
+		}
 	}
 
 	// Increment the version
-	newVersion, err := currentVersion.Increment(increment)
+	plan.NewVersion, err = scheme.Increment(currentVersion, plan.Bump, time.Now().UTC())
 	if err != nil {
-		return fmt.Errorf("failed to increment version: %v", err)
+		return nil, fmt.Errorf("failed to increment version: %v", err)
+	}
+	if scheme == semantic.SemVer {
+		if err := checkReleaseLine(strategy.ReleaseBranchPrefix, strategy.ReleaseBranch, plan.NewVersion); err != nil {
+			return nil, err
+		}
 	}
 
 	// Construct the new tag
-	newTag := fmt.Sprintf("%s%s%s", option.Prefix, newVersion.String(), option.Suffix)
+	plan.NewTag = fmt.Sprintf("%s%s%s", prefix, plan.NewVersion.String(), suffix)
 
-	slog.Debug("Increment", "reason", increment)
+	slog.Log(ctx, strategy.logLevel(), "Version strategy", "base", plan.Strategy, "tag", latestTag, "bump", plan.Bump, "bumpSource", bumpSource, "commits", len(plan.Commits), "newTag", plan.NewTag)
+	return plan, nil
+}
 
-	if option.DryRun {
-		slog.Info("--dry-run", "newTag", newTag)
-		return nil
+// planInitialVersion plans the first release of a repository or component
+// that has no version tag yet.
+func planInitialVersion(ctx context.Context, scheme semantic.Scheme, prefix, suffix, tagPrefix string, paths []string, strategy versionStrategy) (*tagPlan, error) {
+	initial, err := initialVersion(scheme, strategy.InitialVersion, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	plan := &tagPlan{Strategy: "initial-version", TagFilter: tagPrefix}
+	plan.Commits, err = RepositoryFromContext(ctx).Log(ctx, strategy.Commits.logOptions("HEAD", paths))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit messages: %v", err)
+	}
+	plan.Commits = strategy.Commits.filter(plan.Commits)
+	if len(plan.Commits) == 0 {
+		return plan, nil
 	}
+	plan.NewVersion = initial
+	plan.NewTag = fmt.Sprintf("%s%s%s", prefix, initial.String(), suffix)
+	slog.Log(ctx, strategy.logLevel(), "Version strategy", "base", plan.Strategy, "version", initial.String(), "commits", len(plan.Commits), "newTag", plan.NewTag)
+	return plan, nil
+}
 
-	// Create and push the new tag
-	if _, err := Run("tag", newTag); err != nil {
-		return fmt.Errorf("failed to create tag: %v", err)
+// initialVersion parses value with scheme, or asks the scheme for its first
+// version when value is auto, e.g. the date of now for calver.
+func initialVersion(scheme semantic.Scheme, value string, now time.Time) (semantic.Versioned, error) {
+	if value == "auto" {
+		initial, err := scheme.Increment(nil, "", now)
+		if err != nil {
+			return nil, fmt.Errorf("the %s scheme cannot choose an initial version, give one with --initial-version", scheme.Name())
+		}
+		return initial, nil
 	}
-	if _, err := Run("push", option.Remote, newTag); err != nil {
-		return fmt.Errorf("failed to push tag: %v", err)
+	_, _, initial, err := scheme.ExtractVersionFromTag(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid initial version %s: %v", value, err)
 	}
+	return initial, nil
+}
 
-	slog.Info("Tag created and pushed", "tag", newTag)
-	return nil
+// hasVersionTags reports whether the latest tag can be found for every
+// component, or for the whole repository when there are none. Prefixes
+// without any version tag in the repository are ignored: they were never
+// released, so deepening a shallow clone will not find one.
+func hasVersionTags(ctx context.Context, scheme semantic.Scheme, branch string, components []Component) bool {
+	prefixes := []string{""}
+	if len(components) > 0 {
+		prefixes = prefixes[:0]
+		for _, component := range components {
+			prefixes = append(prefixes, component.TagPrefix)
+		}
+	}
+	for _, prefix := range prefixes {
+		if _, err := GetLatestTag(ctx, scheme, "", prefix); err != nil {
+			continue
+		}
+		if _, err := GetLatestTag(ctx, scheme, branch, prefix); err != nil {
+			return false
+		}
+	}
+	return true
 }
 
-func getLatestTag(_ context.Context, branch string) (string, error) {
+var errNoVersionTag = errors.New("no valid tags found")
 
-	commits, err := Run("rev-list", "--tags", "--no-walk", "--abbrev=0", "--date-order", branch)
+// GetLatestTag returns the version tag on the most recent tagged commit
+// reachable from branch, the highest version when that commit has several.
+// When prefix is not empty only tags starting with it are considered.
+func GetLatestTag(ctx context.Context, scheme semantic.Scheme, branch, prefix string) (string, error) {
+
+	tags, err := RepositoryFromContext(ctx).Tags(ctx, branch)
 	if err != nil {
 		return "", fmt.Errorf("failed to get latest tags: %v", err)
 	}
-	var bestVersion semantic.Version
-	var bestTag string
+	var bestVersion semantic.Versioned
+	var best Tag
 
-	slog.Debug("Latest commits found", "commits", commits)
-	commitList := splitLines(commits)
-	for _, commit := range commitList {
-		if commit == "" {
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag.Name, prefix) {
 			continue
 		}
-		commit = strings.TrimSpace(commit)
-		checkCmd, err := Run("merge-base", "--is-ancestor", commit, branch)
-		if err != nil {
+		tagPrefix, _, version, err := scheme.ExtractVersionFromTag(strings.TrimPrefix(tag.Name, prefix))
+		if err != nil || (prefix != "" && tagPrefix != "") {
 			continue
 		}
-		slog.Debug("Checked commit is ancestor", "commit", commit, "branch", branch, "checkCmd", checkCmd)
-		tagsForCommit, err := Run("tag", "--contains", commit)
-		if err != nil {
-			continue
+		slog.Debug("Tag found", "tag", tag.Name, "commit", tag.Commit, "date", tag.Date)
+		if bestVersion == nil || tag.Date.After(best.Date) ||
+			(tag.Date.Equal(best.Date) && version.CompareTo(bestVersion) > 0) {
+			bestVersion = version
+			best = tag
 		}
-		slog.Debug("Tags for commit found", "commit", commit, "tags", tagsForCommit)
+	}
+	if bestVersion != nil {
+		return best.Name, nil
+	}
+	if prefix != "" {
+		return "", fmt.Errorf("%w with prefix %s for branch %s", errNoVersionTag, prefix, branch)
+	}
+	return "", fmt.Errorf("%w for branch %s", errNoVersionTag, branch)
+}
 
-		for _, tag := range splitLines(tagsForCommit) {
-			slog.Debug("Tag found", "tag", tag)
-			_, _, version, err := semantic.ExtractVersionFromTag(tag)
+// createReleaseCommit writes the new versions into the version files of
+// each plan and commits them, so the release tags point at a tree that
+// carries its own version. It reports whether a commit was made.
+func createReleaseCommit(ctx context.Context, plans []*tagPlan) (bool, error) {
+	var files, tags []string
+	for _, plan := range plans {
+		tags = append(tags, plan.NewTag)
+		for _, file := range plan.VersionFiles {
+			changed, err := versionfile.WriteVersion(file, plan.NewVersion.String())
 			if err != nil {
-				continue
+				return false, fmt.Errorf("failed to update version file: %v", err)
 			}
-			if version.IsGreaterThan(bestVersion) {
-				bestVersion = version
-				bestTag = tag
+			slog.Debug("Version file", "file", file, "version", plan.NewVersion.String(), "changed", changed)
+			if changed {
+				files = append(files, file)
 			}
 		}
-		if bestVersion.IsNotEmpty() {
-			return bestTag, nil
+	}
+	if len(files) == 0 {
+		slog.Info("Version files already up to date, no release commit needed", "tags", tags)
+		return false, nil
+	}
+	if err := CommitFiles(ctx, fmt.Sprintf("chore(release): %s", strings.Join(tags, ", ")), files...); err != nil {
+		return false, fmt.Errorf("failed to create release commit: %v", err)
+	}
+	slog.Info("Release commit created", "tags", tags, "files", files)
+	return true, nil
+}
+
+// repoPaths joins paths relative to the top of the repository, such as the
+// version files of a component, with its location.
+func repoPaths(ctx context.Context, repo Repository, paths []string) ([]string, error) {
+	root, err := repo.Run(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	joined := make([]string, len(paths))
+	for i, path := range paths {
+		joined[i] = filepath.FromSlash(path)
+		if !filepath.IsAbs(joined[i]) {
+			joined[i] = filepath.Join(root, joined[i])
 		}
 	}
-	return "", fmt.Errorf("no valid tags found for branch %s", branch)
+	return joined, nil
+}
+
+// dropReleaseCommit undoes the commit made by createReleaseCommit when the
+// release could not be pushed. Other local changes are kept.
+func dropReleaseCommit(ctx context.Context, repo Repository) {
+	if _, err := repo.Run(ctx, "reset", "--keep", "HEAD^"); err != nil {
+		slog.Warn("Failed to drop the release commit, reset the branch by hand", "error", err)
+		return
+	}
+	slog.Info("Dropped the release commit")
 }
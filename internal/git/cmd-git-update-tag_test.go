@@ -0,0 +1,563 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+func TestBumpGitTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+		expected string
+	}{
+		{"patch", []string{"fix: handle empty input", "docs: explain flags"}, "v1.2.4"},
+		{"minor", []string{"fix: handle empty input", "feat: add --json"}, "v1.3.0"},
+		{"major", []string{"feat: add --json", "breaking: drop --xml"}, "v2.0.0"},
+	}
+	for _, tc := range tests {
+		repo := NewFakeRepository()
+		repo.Commit("chore: initial commit")
+		if err := repo.Tag("v1.2.3"); err != nil {
+			t.Fatal(err)
+		}
+		for _, message := range tc.messages {
+			repo.Commit(message)
+		}
+		ctx := WithRepository(context.Background(), repo)
+
+		err := executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin"}, nil)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !slices.Contains(repo.TagNames(), tc.expected) {
+			t.Errorf("%s: expected tag %s, got %v", tc.name, tc.expected, repo.TagNames())
+		}
+		if !slices.Contains(repo.Pushed, "origin "+tc.expected) {
+			t.Errorf("%s: expected %s to be pushed, got %v", tc.name, tc.expected, repo.Pushed)
+		}
+	}
+}
+
+func TestBumpGitTagIgnoresOtherBranches(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("chore: initial commit")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Checkout("release/2.x")
+	repo.Commit("breaking: new world")
+	if err := repo.Tag("v2.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Checkout("main")
+	repo.Commit("fix: typo")
+	ctx := WithRepository(context.Background(), repo)
+
+	err := executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(repo.TagNames(), "v1.0.1") {
+		t.Errorf("expected tag v1.0.1, got %v", repo.TagNames())
+	}
+}
+
+func TestBumpGitTagNoChanges(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("feat: initial commit")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithRepository(context.Background(), repo)
+
+	err := executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.Pushed) != 0 {
+		t.Errorf("expected nothing to be pushed, got %v", repo.Pushed)
+	}
+}
+
+func TestBumpGitTagComponents(t *testing.T) {
+	components := filepath.Join(t.TempDir(), "components.json")
+	err := os.WriteFile(components, []byte(`{"components": [
+		{"name": "svc-a", "paths": ["services/a/**"]},
+		{"name": "svc-b", "paths": ["services/b/**", "libs/**"]}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewFakeRepository()
+	repo.Commit("chore: initial commit", "services/a/main.go", "services/b/main.go")
+	if err := repo.Tag("svc-a/v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Tag("svc-b/v0.4.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("feat: new endpoint", "services/a/api.go")
+	repo.Commit("fix: shared bug", "libs/util.go")
+	ctx := WithRepository(context.Background(), repo)
+
+	err = executeBumpGitTag(ctx, &BumpGitTagOptions{Remote: "origin", Components: components}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"svc-a/v1.1.0", "svc-b/v0.4.1"} {
+		if !slices.Contains(repo.TagNames(), expected) {
+			t.Errorf("expected tag %s, got %v", expected, repo.TagNames())
+		}
+	}
+}
+
+func TestBumpGitTagComponentTags(t *testing.T) {
+	components := filepath.Join(t.TempDir(), "components.json")
+	err := os.WriteFile(components, []byte(`{"components": [
+		{"name": "svc-a", "paths": ["services/a/**"]},
+		{"name": "svc-b", "paths": ["services/b/**"], "tag_prefix": "b-v"},
+		{"name": "svc-c", "paths": ["services/c/**"]}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRepo := func() *FakeRepository {
+		repo := NewFakeRepository()
+		repo.Commit("chore: initial commit", "services/a/main.go", "services/b/main.go")
+		if err := repo.Tag("svc-a/v1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Tag("b-v0.4.0"); err != nil {
+			t.Fatal(err)
+		}
+		// tags that only look like they belong to a component
+		repo.Commit("chore: unrelated", "README.md")
+		if err := repo.Tag("v9.0.0"); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Tag("svc-ab/v3.0.0"); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Tag("b-rc-v2.0.0"); err != nil {
+			t.Fatal(err)
+		}
+		repo.Commit("feat: everywhere", "services/a/api.go", "services/b/api.go", "services/c/main.go")
+		return repo
+	}
+
+	tests := []struct {
+		name     string
+		option   BumpGitTagOptions
+		expected []string
+	}{
+		{"untagged component is skipped", BumpGitTagOptions{}, []string{"svc-a/v1.1.0", "b-v0.5.0"}},
+		{"untagged component gets the initial version", BumpGitTagOptions{InitialVersion: "0.1.0"}, []string{"svc-a/v1.1.0", "b-v0.5.0", "svc-c/v0.1.0"}},
+	}
+	for _, tc := range tests {
+		repo := newRepo()
+		ctx := WithRepository(context.Background(), repo)
+		option := tc.option
+		option.Remote, option.Components = "origin", components
+		if err := executeBumpGitTag(ctx, &option, nil); err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		var pushed []string
+		for _, ref := range repo.Pushed {
+			pushed = append(pushed, strings.TrimPrefix(ref, "origin "))
+		}
+		if !slices.Equal(pushed, tc.expected) {
+			t.Errorf("%s: expected %v to be pushed, got %v", tc.name, tc.expected, pushed)
+		}
+	}
+}
+
+func TestBumpGitTagPreflight(t *testing.T) {
+	newRepo := func() *FakeRepository {
+		repo := NewFakeRepository()
+		repo.Commit("chore: initial commit")
+		if err := repo.Tag("v1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	}
+	options := &BumpGitTagOptions{Prefix: "v", Remote: "origin"}
+
+	// HEAD was never pushed
+	repo := newRepo()
+	pushed := repo.Commit("fix: pushed")
+	repo.Commit("feat: local only")
+	repo.RemoteBranches["main"] = pushed
+	err := executeBumpGitTag(WithRepository(context.Background(), repo), options, nil)
+	if err == nil || !strings.Contains(err.Error(), "HEAD is not on origin/main") {
+		t.Errorf("expected unpushed HEAD to fail, got %v", err)
+	}
+
+	// the remote branch was released in the meantime
+	repo = newRepo()
+	repo.Commit("fix: one")
+	repo.Checkout("ahead")
+	ahead := repo.Commit("fix: two")
+	if err := repo.Tag("v1.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Checkout("main")
+	repo.RemoteBranches["main"] = ahead
+	err = executeBumpGitTag(WithRepository(context.Background(), repo), options, nil)
+	if err == nil || !strings.Contains(err.Error(), "newer release v1.0.1") {
+		t.Errorf("expected newer remote release to fail, got %v", err)
+	}
+
+	// the next tag exists on another commit
+	repo = newRepo()
+	repo.Checkout("other")
+	repo.Commit("fix: elsewhere")
+	if err := repo.Tag("v1.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Checkout("main")
+	repo.Commit("fix: here")
+	err = executeBumpGitTag(WithRepository(context.Background(), repo), options, nil)
+	if err == nil || !strings.Contains(err.Error(), "tag v1.0.1 already exists") {
+		t.Errorf("expected existing tag to fail, got %v", err)
+	}
+	if len(repo.Pushed) != 0 {
+		t.Errorf("expected nothing to be pushed, got %v", repo.Pushed)
+	}
+}
+
+func TestBumpGitTagRollback(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("chore: initial commit")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("feat: more")
+	repo.PushErr = errors.New("rejected")
+	ctx := WithRepository(context.Background(), repo)
+
+	err := executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin"}, nil)
+	if err == nil {
+		t.Fatalf("expected push failure")
+	}
+	if tags := repo.TagNames(); !slices.Equal(tags, []string{"v1.0.0"}) {
+		t.Errorf("expected the local tag to be deleted, got %v", tags)
+	}
+}
+
+func TestBumpGitTagRollbackReleaseCommit(t *testing.T) {
+	versionFile := filepath.Join(t.TempDir(), "package.json")
+	if err := os.WriteFile(versionFile, []byte("{\n  \"version\": \"1.0.0\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewFakeRepository()
+	repo.Commit("chore: initial commit")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("feat: more")
+	repo.PushErr = errors.New("rejected")
+	repo.Responses["add -- "+versionFile] = ""
+	repo.Responses["commit -m chore(release): v1.1.0 -- "+versionFile] = ""
+	repo.Responses["reset --keep HEAD^"] = ""
+	ctx := WithRepository(context.Background(), repo)
+
+	option := &BumpGitTagOptions{Prefix: "v", Remote: "origin", ReleaseCommit: true, VersionFiles: versionFile}
+	err := executeBumpGitTag(ctx, option, nil)
+	if err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Fatalf("expected push failure, got %v", err)
+	}
+	if !slices.Contains(repo.Calls, "reset --keep HEAD^") {
+		t.Errorf("expected the release commit to be dropped, got %v", repo.Calls)
+	}
+	if tags := repo.TagNames(); !slices.Equal(tags, []string{"v1.0.0"}) {
+		t.Errorf("expected the local tag to be deleted, got %v", tags)
+	}
+}
+
+func TestBumpGitTagReleaseCommitDetached(t *testing.T) {
+	root := t.TempDir()
+	versionFile := filepath.Join(root, "package.json")
+	if err := os.WriteFile(versionFile, []byte("{\n  \"version\": \"1.0.0\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newRepo := func() *FakeRepository {
+		repo := NewFakeRepository()
+		repo.Commit("chore: initial commit")
+		if err := repo.Tag("v1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+		repo.Commit("feat: more")
+		repo.Checkout("HEAD")
+		repo.Responses["rev-parse --show-toplevel"] = root
+		repo.Responses["add -- "+versionFile] = ""
+		repo.Responses["commit -m chore(release): v1.1.0 -- "+versionFile] = ""
+		return repo
+	}
+	option := &BumpGitTagOptions{Prefix: "v", Remote: "origin", NoFetch: true, ReleaseCommit: true}
+
+	// the branch comes from CI, the version file from the top of the repository
+	github := map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_REF": "refs/heads/main"}
+	repo := newRepo()
+	ctx := WithGetenv(WithRepository(context.Background(), repo), func(key string) string { return github[key] })
+	if err := executeBumpGitTag(ctx, option, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(repo.Pushed, "origin HEAD:refs/heads/main") {
+		t.Errorf("expected the release commit to be pushed to main, got %v", repo.Pushed)
+	}
+
+	// no branch at all
+	repo = newRepo()
+	ctx = WithGetenv(WithRepository(context.Background(), repo), func(string) string { return "" })
+	err := executeBumpGitTag(ctx, option, nil)
+	if err == nil || !strings.Contains(err.Error(), "HEAD is detached") {
+		t.Errorf("expected a detached HEAD to fail, got %v", err)
+	}
+	if len(repo.Pushed) != 0 || !slices.Equal(repo.TagNames(), []string{"v1.0.0"}) {
+		t.Errorf("expected nothing to be tagged or pushed, got %v and %v", repo.TagNames(), repo.Pushed)
+	}
+}
+
+func TestBumpGitTagDryRun(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("chore: initial commit")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("feat: more")
+	repo.Shallow(2)
+	ctx := WithRepository(context.Background(), repo)
+
+	err := executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin", FetchDepth: 1000, DryRun: true}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.Fetched) != 0 || len(repo.Deepened) != 0 {
+		t.Errorf("expected a dry run to stay offline, fetched %v and deepened %v", repo.Fetched, repo.Deepened)
+	}
+	if tags := repo.TagNames(); !slices.Equal(tags, []string{"v1.0.0"}) {
+		t.Errorf("expected no new tag, got %v", tags)
+	}
+}
+
+func TestBumpGitTagShallowClone(t *testing.T) {
+	newRepo := func() *FakeRepository {
+		repo := NewFakeRepository()
+		repo.Commit("chore: initial commit")
+		if err := repo.Tag("v1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 120; i++ {
+			repo.Commit("fix: another one")
+		}
+		repo.Commit("feat: latest")
+		repo.Shallow(1)
+		return repo
+	}
+
+	repo := newRepo()
+	ctx := WithRepository(context.Background(), repo)
+	err := executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin", FetchDepth: 1000}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(repo.Deepened, []int{50, 100}) {
+		t.Errorf("expected to deepen by 50 then 100, got %v", repo.Deepened)
+	}
+	if !slices.Contains(repo.TagNames(), "v1.1.0") {
+		t.Errorf("expected tag v1.1.0, got %v", repo.TagNames())
+	}
+
+	repo = newRepo()
+	ctx = WithRepository(context.Background(), repo)
+	err = executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin", FetchDepth: 80}, nil)
+	if err == nil || !strings.Contains(err.Error(), "fetch-depth: 0") {
+		t.Errorf("expected a shallow clone error, got %v", err)
+	}
+	if !slices.Equal(repo.Deepened, []int{50, 30}) {
+		t.Errorf("expected to deepen by 50 then 30, got %v", repo.Deepened)
+	}
+}
+
+func TestBumpGitTagStrategy(t *testing.T) {
+	// no tag yet
+	repo := NewFakeRepository()
+	repo.Commit("feat: first")
+	ctx := WithRepository(context.Background(), repo)
+	err := executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "--initial-version") {
+		t.Errorf("expected a hint about --initial-version, got %v", err)
+	}
+	err = executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin", InitialVersion: "0.1.0"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(repo.TagNames(), []string{"v0.1.0"}) {
+		t.Errorf("expected tag v0.1.0, got %v", repo.TagNames())
+	}
+
+	// forced bump ignores the commit messages
+	repo.Commit("fix: small")
+	err = executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin", ForceBump: "major"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(repo.TagNames(), "v1.0.0") {
+		t.Errorf("expected tag v1.0.0, got %v", repo.TagNames())
+	}
+	err = executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin", ForceBump: "huge"}, nil)
+	if err == nil {
+		t.Errorf("expected an invalid --force-bump to fail")
+	}
+
+	// an explicit base tag wins over the latest one
+	if err := repo.Tag("v5.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("fix: from an older line")
+	err = executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin", FromTag: "v1.0.0"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(repo.TagNames(), "v1.0.1") {
+		t.Errorf("expected tag v1.0.1, got %v", repo.TagNames())
+	}
+}
+
+func TestBumpGitTagInitialVersion(t *testing.T) {
+	// a shallow clone without tags is not deepened in vain
+	repo := NewFakeRepository()
+	for i := 0; i < 10; i++ {
+		repo.Commit("feat: more")
+	}
+	repo.Shallow(1)
+	ctx := WithRepository(context.Background(), repo)
+	err := executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin", FetchDepth: 1000, InitialVersion: "0.1.0"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.Deepened) != 0 {
+		t.Errorf("expected no deepening, got %v", repo.Deepened)
+	}
+	if !slices.Equal(repo.TagNames(), []string{"v0.1.0"}) {
+		t.Errorf("expected tag v0.1.0, got %v", repo.TagNames())
+	}
+
+	tests := []struct {
+		name     string
+		option   BumpGitTagOptions
+		expected string
+		err      string
+	}{
+		{"calver version", BumpGitTagOptions{Scheme: "calver", InitialVersion: "2024.05.3"}, "2024.05.3", ""},
+		{"calver auto", BumpGitTagOptions{Scheme: "calver", InitialVersion: "auto"}, time.Now().UTC().Format("2006.01") + ".0", ""},
+		{"semver auto", BumpGitTagOptions{InitialVersion: "auto"}, "", "cannot choose an initial version"},
+		{"invalid", BumpGitTagOptions{Scheme: "calver", InitialVersion: "0.1.0"}, "", "invalid initial version"},
+	}
+	for _, tc := range tests {
+		repo := NewFakeRepository()
+		repo.Commit("feat: first")
+		ctx := WithRepository(context.Background(), repo)
+		option := tc.option
+		option.Remote = "origin"
+		err := executeBumpGitTag(ctx, &option, nil)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: expected error %q, got %v", tc.name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !slices.Equal(repo.TagNames(), []string{tc.expected}) {
+			t.Errorf("%s: expected tag %s, got %v", tc.name, tc.expected, repo.TagNames())
+		}
+	}
+}
+
+func TestBumpGitTagCommitSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   BumpGitTagOptions
+		expected string
+	}{
+		{"subjects", BumpGitTagOptions{}, "v1.1.0"},
+		{"full message", BumpGitTagOptions{FullMessage: true}, "v2.0.0"},
+		{"first parent", BumpGitTagOptions{FirstParent: true, FullMessage: true, CancelReverts: true}, "v1.0.1"},
+		{"cancel reverts", BumpGitTagOptions{CancelReverts: true, NoMerges: true}, "v1.0.1"},
+		{"no reverts", BumpGitTagOptions{NoReverts: true, NoMerges: true}, "v1.1.0"},
+	}
+	for _, tc := range tests {
+		repo := NewFakeRepository()
+		repo.Commit("chore: initial commit")
+		if err := repo.Tag("v1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+		feature := repo.Commit("feat: add --json")
+		repo.Commit("Revert \"feat: add --json\"\n\nThis reverts commit " + feature + ".")
+		repo.Checkout("topic")
+		repo.Commit("fix(parser): reject bad input\n\nBREAKING CHANGE: empty input is an error")
+		repo.Checkout("main")
+		if _, err := repo.Merge("topic", "fix: merge topic"); err != nil {
+			t.Fatal(err)
+		}
+		ctx := WithRepository(context.Background(), repo)
+
+		option := tc.option
+		option.Prefix, option.Remote = "v", "origin"
+		if err := executeBumpGitTag(ctx, &option, nil); err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !slices.Contains(repo.TagNames(), tc.expected) {
+			t.Errorf("%s: expected tag %s, got %v", tc.name, tc.expected, repo.TagNames())
+		}
+	}
+}
+
+func TestGetLatestTag(t *testing.T) {
+	repo := NewFakeRepository()
+	repo.Commit("chore: initial commit")
+	if err := repo.Tag("v2.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("fix: hotfix line")
+	if err := repo.Tag("v1.5.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Tag("v1.5.1"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("fix: untagged")
+	ctx := WithRepository(context.Background(), repo)
+
+	// the most recent tagged commit wins over a higher version further back
+	tag, err := GetLatestTag(ctx, semantic.SemVer, "HEAD", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tag != "v1.5.1" {
+		t.Errorf("expected v1.5.1, got %s", tag)
+	}
+	tag, err = GetLatestTag(ctx, semantic.SemVer, "HEAD~2", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tag != "v2.0.0" {
+		t.Errorf("expected v2.0.0, got %s", tag)
+	}
+}
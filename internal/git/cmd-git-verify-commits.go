@@ -0,0 +1,45 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+func executeVerifyCommits(ctx context.Context, option *VerifyOptions, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no revision range given")
+	}
+	if option.Keyring == "" {
+		return fmt.Errorf("--keyring is required")
+	}
+	keyring, err := loadKeyring(option.Keyring)
+	if err != nil {
+		return err
+	}
+
+	repo := RepositoryFromContext(ctx)
+	commits, err := repo.Log(ctx, LogOptions{Range: args[0]})
+	if err != nil {
+		return err
+	}
+	slog.Debug("Verifying commits", "range", args[0], "commits", len(commits))
+
+	failed := 0
+	for _, commit := range commits {
+		_, object, err := repo.CatFile(ctx, commit.Hash)
+		if err != nil {
+			return err
+		}
+		payload, signature := splitCommitSignature(object)
+		check := verifySignature(keyring, commit.ShortHash, payload, signature)
+		fmt.Println(check.String())
+		if check.Reason != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d commits failed signature verification", failed, len(commits))
+	}
+	return nil
+}
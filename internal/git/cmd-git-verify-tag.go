@@ -0,0 +1,55 @@
+package git
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+type VerifyOptions struct {
+	Keyring string `flag:"--keyring,File with the armored or binary OpenPGP public keys allowed to sign"`
+}
+
+func executeVerifyTag(ctx context.Context, option *VerifyOptions, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no tag given")
+	}
+	if option.Keyring == "" {
+		return fmt.Errorf("--keyring is required")
+	}
+	keyring, err := loadKeyring(option.Keyring)
+	if err != nil {
+		return err
+	}
+
+	repo := RepositoryFromContext(ctx)
+	failed := 0
+	for _, tag := range args {
+		check, err := verifyTag(ctx, repo, keyring, tag)
+		if err != nil {
+			return err
+		}
+		fmt.Println(check.String())
+		if check.Reason != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tags failed signature verification", failed, len(args))
+	}
+	return nil
+}
+
+func verifyTag(ctx context.Context, repo Repository, keyring openpgp.KeyRing, tag string) (signatureCheck, error) {
+	objectType, object, err := repo.CatFile(ctx, "refs/tags/"+tag)
+	if err != nil {
+		return signatureCheck{}, err
+	}
+	if objectType != "tag" {
+		// a lightweight tag has nothing to sign
+		return signatureCheck{Object: tag, Reason: reasonUnsigned, Detail: "lightweight tag"}, nil
+	}
+	payload, signature := splitTagSignature(object)
+	return verifySignature(keyring, tag, payload, signature), nil
+}
@@ -0,0 +1,90 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+)
+
+type WorktreeExecOptions struct {
+	Prefix string `flag:"--prefix,Prefix of the build variable names"`
+}
+
+// executeWorktreeExec runs a command in a temporary worktree at a ref, with
+// the build variables of suggest-build-env set, so that a tag can be built
+// without touching the current checkout.
+func executeWorktreeExec(ctx context.Context, option *WorktreeExecOptions, args []string) error {
+	ref, command := "", args
+	if i := slices.Index(args, "--"); i >= 0 {
+		if i == 1 {
+			ref = args[0]
+		}
+		command = args[i+1:]
+	} else if len(args) > 0 {
+		ref, command = args[0], args[1:]
+	}
+	if ref == "" || len(command) == 0 {
+		return fmt.Errorf("expected a ref and a command, e.g. git worktree-exec v1.2.0 -- make build")
+	}
+
+	// clean up when interrupted rather than leave the worktree behind
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	prefix := option.Prefix
+	if prefix == "" {
+		prefix = defaultEnvPrefix
+	}
+	return runInWorktree(ctx, RepositoryFromContext(ctx), ref, prefix, command)
+}
+
+func runInWorktree(ctx context.Context, repo Repository, ref, prefix string, command []string) (err error) {
+	tmp, err := os.MkdirTemp("", "cicd-worktree-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "src")
+
+	worktree, err := repo.AddWorktree(ctx, dir, ref)
+	if err != nil {
+		return err
+	}
+	slog.Info("Worktree created", "ref", ref, "dir", dir)
+	defer func() {
+		// the command context may be cancelled already
+		if removeErr := repo.RemoveWorktree(context.WithoutCancel(ctx), dir); removeErr != nil {
+			slog.Warn("Failed to remove worktree", "dir", dir, "error", removeErr)
+		} else {
+			slog.Info("Worktree removed", "dir", dir)
+		}
+	}()
+
+	env, err := getBuildEnv(WithRepository(ctx, worktree), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	prefixBuildEnv(env, prefix)
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for _, v := range env {
+		cmd.Env = append(cmd.Env, v.Name+"="+v.Value)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		// wrapped so that the exit status of the command becomes ours
+		return fmt.Errorf("%s failed in worktree at %s: %w", command[0], ref, err)
+	}
+	return nil
+}
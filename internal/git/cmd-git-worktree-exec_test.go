@@ -0,0 +1,51 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunInWorktree(t *testing.T) {
+	repo := NewFakeRepository()
+	first := repo.Commit("feat: initial commit")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("feat: next feature")
+	repo.Dirty = []StatusEntry{{" M", "main.go"}}
+	ctx := WithRepository(context.Background(), repo)
+
+	out := filepath.Join(t.TempDir(), "out")
+	script := `echo "$BUILD_VERSION $BUILD_SHA $BUILD_DIRTY $SOURCE_DATE_EPOCH" > "$0"; pwd >> "$0"`
+	if err := runInWorktree(ctx, repo, "v1.0.0", "BUILD_", []string{"sh", "-c", script, out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("command did not run: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if !strings.HasPrefix(lines[0], "v1.0.0 "+first+" false ") {
+		t.Errorf("unexpected build env %q", lines[0])
+	}
+	if len(repo.Worktrees) != 0 {
+		t.Errorf("expected the worktree to be removed, got %v", repo.Worktrees)
+	}
+	if _, err := os.Stat(lines[1]); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be deleted, got %v", lines[1], err)
+	}
+
+	err = runInWorktree(ctx, repo, "HEAD", "BUILD_", []string{"sh", "-c", "exit 3"})
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("expected the exit status of the failing command, got %v", err)
+	}
+	if len(repo.Worktrees) != 0 {
+		t.Errorf("expected the worktree to be removed after a failure, got %v", repo.Worktrees)
+	}
+}
@@ -0,0 +1,101 @@
+package git
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+// commitSelection controls which commits since the last release decide the
+// version bump.
+type commitSelection struct {
+	// FirstParent follows only the first parent of merges, so a merged
+	// branch counts once through its merge commit.
+	FirstParent bool
+	NoMerges    bool
+	NoReverts   bool
+	// CancelReverts drops reverts together with the commit they revert
+	// when both are in the range.
+	CancelReverts bool
+	// FullMessage looks at the whole message, not just the subject.
+	FullMessage bool
+}
+
+func (s commitSelection) logOptions(revRange string, paths []string) LogOptions {
+	return LogOptions{Range: revRange, Paths: paths, FirstParent: s.FirstParent, NoMerges: s.NoMerges}
+}
+
+// filter applies the revert handling to commits, newest first as git log
+// returns them.
+func (s commitSelection) filter(commits []Commit) []Commit {
+	if !s.NoReverts && !s.CancelReverts {
+		return commits
+	}
+	dropped := map[string]bool{}
+	if s.CancelReverts {
+		// newest first, so a revert of a revert cancels the revert and
+		// leaves the original commit in place
+		for i, commit := range commits {
+			if dropped[commit.Hash] || !isRevert(commit) {
+				continue
+			}
+			for _, older := range commits[i+1:] {
+				if !dropped[older.Hash] && reverts(commit, older) {
+					slog.Debug("Revert cancels commit", "revert", commit.ShortHash, "commit", older.ShortHash)
+					dropped[commit.Hash] = true
+					dropped[older.Hash] = true
+					break
+				}
+			}
+		}
+	}
+	var selected []Commit
+	for _, commit := range commits {
+		if dropped[commit.Hash] || (s.NoReverts && isRevert(commit)) {
+			continue
+		}
+		selected = append(selected, commit)
+	}
+	return selected
+}
+
+// bump works out the version increment from the selected commits.
+func (s commitSelection) bump(commits []Commit) (string, error) {
+	messages := make([]string, len(commits))
+	for i, commit := range commits {
+		if s.FullMessage {
+			messages[i] = commit.Message
+		} else {
+			messages[i] = commit.Subject()
+		}
+	}
+	if s.FullMessage {
+		return semantic.Bumps.GetMessageBump(messages)
+	}
+	return semantic.Bumps.GetVersionBump(messages)
+}
+
+var revertedCommitFmt = regexp.MustCompile(`This reverts commit ([0-9a-f]{7,40})`)
+
+// isRevert recognises commits made by git revert and conventional
+// "revert:" commits.
+func isRevert(commit Commit) bool {
+	subject := commit.Subject()
+	return strings.HasPrefix(subject, `Revert "`) || strings.HasPrefix(subject, "revert:") || strings.HasPrefix(subject, "revert(")
+}
+
+// reverts reports whether revert undoes commit, by the hash git revert puts
+// in the body or, for squash merged reverts, by the quoted subject.
+func reverts(revert, commit Commit) bool {
+	if match := revertedCommitFmt.FindStringSubmatch(revert.Message); match != nil {
+		return strings.HasPrefix(commit.Hash, match[1])
+	}
+	quoted, found := strings.CutPrefix(revert.Subject(), `Revert "`)
+	if !found {
+		return false
+	}
+	quoted = quoted[:max(strings.LastIndex(quoted, `"`), 0)]
+	return quoted != "" && quoted == commit.Subject()
+}
@@ -0,0 +1,109 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Component is a separately versioned part of a repository, e.g. one
+// deployable in a monorepo. Its tags look like <TagPrefix><version>.
+// GoPackages are package patterns whose imports also belong to the
+// component when looking for changed components.
+type Component struct {
+	Name         string   `json:"name"`
+	Paths        []string `json:"paths"`
+	GoPackages   []string `json:"go_packages"`
+	TagPrefix    string   `json:"tag_prefix"`
+	VersionFiles []string `json:"version_files"`
+}
+
+type componentsFile struct {
+	Components []Component `json:"components"`
+}
+
+// loadComponents reads component definitions from a JSON file such as
+//
+//	{"components": [{"name": "svc-a", "paths": ["services/a/**"], "tag_prefix": "svc-a/v"}]}
+//
+// When names are given only those components are returned.
+func loadComponents(path string, names []string) ([]Component, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read components file: %v", err)
+	}
+	var file componentsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse components file %s: %v", path, err)
+	}
+
+	var components []Component
+	seen := map[string]bool{}
+	for _, component := range file.Components {
+		if component.Name == "" {
+			return nil, fmt.Errorf("%s: component without a name", path)
+		}
+		if seen[component.Name] {
+			return nil, fmt.Errorf("%s: duplicate component %s", path, component.Name)
+		}
+		seen[component.Name] = true
+		if len(component.Paths) == 0 && len(component.GoPackages) == 0 {
+			return nil, fmt.Errorf("%s: component %s has no paths", path, component.Name)
+		}
+		if component.TagPrefix == "" {
+			component.TagPrefix = component.Name + "/v"
+		}
+		if len(names) > 0 && !slices.Contains(names, component.Name) {
+			continue
+		}
+		components = append(components, component)
+	}
+	for _, name := range names {
+		if !seen[name] {
+			return nil, fmt.Errorf("%s: unknown component %s", path, name)
+		}
+	}
+	return components, nil
+}
+
+// pathspecs turns glob patterns into git pathspecs, so ** matches across
+// directories as it does in most CI path filters.
+func pathspecs(patterns []string) []string {
+	specs := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		specs[i] = ":(glob)" + pattern
+	}
+	return specs
+}
+
+// matchGlob reports whether file matches pattern the way a git glob pathspec
+// does: * and ? stay within a directory, ** crosses directories and a
+// pattern matching a directory matches everything below it.
+func matchGlob(pattern, file string) bool {
+	re := strings.Builder{}
+	re.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				re.WriteString("(.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				re.WriteString(".*")
+				i++
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("(/.*)?$")
+	matched, err := regexp.MatchString(re.String(), file)
+	return err == nil && matched
+}
@@ -0,0 +1,132 @@
+package git
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// envFormats are the output formats of suggest-build-env.
+var envFormats = []string{"dotenv", "shell", "json", "make", "github"}
+
+// formatEnv renders vars in one of the text formats. The github format
+// writes files instead, see writeGitHubEnv.
+func formatEnv(format string, vars []envVar) (string, error) {
+	sb := strings.Builder{}
+	switch format {
+	case "dotenv", "":
+		for _, v := range vars {
+			fmt.Fprintf(&sb, "%s=%s\n", v.Name, dotenvQuote(v.Value))
+		}
+	case "shell":
+		for _, v := range vars {
+			fmt.Fprintf(&sb, "export %s=%s\n", v.Name, shellQuote(v.Value))
+		}
+	case "make":
+		for _, v := range vars {
+			value := strings.ReplaceAll(v.Value, "$", "$$")
+			if strings.Contains(value, "\n") {
+				// only define can hold a multi-line value
+				fmt.Fprintf(&sb, "define %s\n%s\nendef\nexport %s\n", v.Name, value, v.Name)
+				continue
+			}
+			fmt.Fprintf(&sb, "export %s := %s\n", v.Name, strings.ReplaceAll(value, "#", `\#`))
+		}
+	case "json":
+		values := map[string]string{}
+		for _, v := range vars {
+			values[v.Name] = v.Value
+		}
+		data, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal environment: %v", err)
+		}
+		sb.Write(data)
+		sb.WriteString("\n")
+	default:
+		return "", fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(envFormats, ", "))
+	}
+	return sb.String(), nil
+}
+
+// dotenvQuote leaves simple values alone and double quotes the rest, with
+// the escapes understood by docker compose and the dotenv libraries.
+func dotenvQuote(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-.,:/@+") == "" {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// writeGitHubEnv appends vars to $GITHUB_ENV, so later steps of the job see
+// them, and to $GITHUB_OUTPUT when it is set, so other jobs can use them.
+func writeGitHubEnv(vars []envVar) error {
+	envFile := os.Getenv("GITHUB_ENV")
+	if envFile == "" {
+		return fmt.Errorf("$GITHUB_ENV is not set, the github format only works in GitHub Actions")
+	}
+	content, err := formatGitHubEnv(vars)
+	if err != nil {
+		return err
+	}
+	for _, file := range []string{envFile, os.Getenv("GITHUB_OUTPUT")} {
+		if file == "" {
+			continue
+		}
+		if err := appendFile(file, content); err != nil {
+			return err
+		}
+		slog.Info("Build environment written", "file", file, "vars", len(vars))
+	}
+	return nil
+}
+
+// formatGitHubEnv uses NAME=value lines, or the NAME<<DELIMITER syntax for
+// values spanning several lines.
+func formatGitHubEnv(vars []envVar) (string, error) {
+	sb := strings.Builder{}
+	for _, v := range vars {
+		if !strings.ContainsAny(v.Value, "\r\n") {
+			fmt.Fprintf(&sb, "%s=%s\n", v.Name, v.Value)
+			continue
+		}
+		delimiter, err := randomDelimiter(v.Value)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "%s<<%s\n%s\n%s\n", v.Name, delimiter, v.Value, delimiter)
+	}
+	return sb.String(), nil
+}
+
+// randomDelimiter returns a heredoc delimiter that does not occur in value,
+// so a value cannot end the block early and inject other variables.
+func randomDelimiter(value string) (string, error) {
+	for {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("failed to create delimiter: %v", err)
+		}
+		delimiter := "ghadelimiter_" + hex.EncodeToString(b)
+		if !strings.Contains(value, delimiter) {
+			return delimiter, nil
+		}
+	}
+}
+
+func appendFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,74 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatEnv(t *testing.T) {
+	vars := []envVar{
+		{"BUILD_VERSION", "v1.2.3"},
+		{"BUILD_TIME", "Mon, 19 Oct 2026 12:30:00 UTC"},
+		{"BUILD_NOTES", "it's $5\nand #2"},
+	}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"dotenv", "BUILD_VERSION=v1.2.3\nBUILD_TIME=\"Mon, 19 Oct 2026 12:30:00 UTC\"\nBUILD_NOTES=\"it's \\$5\\nand #2\"\n"},
+		{"shell", "export BUILD_VERSION='v1.2.3'\nexport BUILD_TIME='Mon, 19 Oct 2026 12:30:00 UTC'\nexport BUILD_NOTES='it'\\''s $5\nand #2'\n"},
+		{"make", "export BUILD_VERSION := v1.2.3\nexport BUILD_TIME := Mon, 19 Oct 2026 12:30:00 UTC\ndefine BUILD_NOTES\nit's $$5\nand #2\nendef\nexport BUILD_NOTES\n"},
+		{"json", "{\n  \"BUILD_NOTES\": \"it's $5\\nand #2\",\n  \"BUILD_TIME\": \"Mon, 19 Oct 2026 12:30:00 UTC\",\n  \"BUILD_VERSION\": \"v1.2.3\"\n}\n"},
+	}
+	for _, tc := range tests {
+		got, err := formatEnv(tc.format, vars)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.format, err)
+			continue
+		}
+		if got != tc.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.format, tc.expected, got)
+		}
+	}
+	if _, err := formatEnv("yaml", vars); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestWriteGitHubEnv(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env")
+	outputFile := filepath.Join(dir, "output")
+	t.Setenv("GITHUB_ENV", envFile)
+	t.Setenv("GITHUB_OUTPUT", outputFile)
+	if err := os.WriteFile(envFile, []byte("EXISTING=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeGitHubEnv([]envVar{{"BUILD_VERSION", "v1.2.3"}, {"BUILD_NOTES", "line 1\nline 2"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, file := range []string{envFile, outputFile} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(string(data), "\n")
+		if file == envFile {
+			if lines[0] != "EXISTING=1" {
+				t.Errorf("expected existing content to be kept, got %q", lines[0])
+			}
+			lines = lines[1:]
+		}
+		if lines[0] != "BUILD_VERSION=v1.2.3" {
+			t.Errorf("unexpected line %q", lines[0])
+		}
+		name, delimiter, _ := strings.Cut(lines[1], "<<")
+		if name != "BUILD_NOTES" || lines[2] != "line 1" || lines[3] != "line 2" || lines[4] != delimiter {
+			t.Errorf("unexpected multi-line value in %s:\n%s", file, data)
+		}
+	}
+}
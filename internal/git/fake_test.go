@@ -0,0 +1,734 @@
+package git
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FakeRepository is an in-memory Repository for tests. Build a history with
+// Commit, Tag, Checkout and Merge, then run commands against it with
+// WithRepository.
+type FakeRepository struct {
+	// Responses maps the space separated arguments of a Run call to its output.
+	Responses map[string]string
+	// Dirty is returned by Status.
+	Dirty []StatusEntry
+	// Pushed records every pushed ref as "<remote> <ref>".
+	Pushed []string
+	// PushErr makes Push fail without pushing anything.
+	PushErr error
+	// RejectPushes makes that many Push calls fail as if the remote ref had
+	// moved on, before PushErr applies.
+	RejectPushes int
+	// RemoteBranches sets the commit Fetch stores for a remote branch. A
+	// branch not listed is fetched at the same commit as the local one.
+	RemoteBranches map[string]string
+	// RemoteNotes sets the notes, by commit hash, Fetch stores for a notes
+	// ref. A notes ref not listed is left alone.
+	RemoteNotes map[string]map[string]string
+	// Fetched records the refspecs of every Fetch call.
+	Fetched []string
+	// Deepened records the depth of every Fetch call that deepened history.
+	Deepened []int
+	// Calls records the arguments of every Run call.
+	Calls []string
+	// TagObjects holds the content of annotated tags by name.
+	TagObjects map[string]string
+	// CommitObjects overrides the content CatFile returns for a commit hash.
+	CommitObjects map[string]string
+	// SubmoduleList is returned by Submodules.
+	SubmoduleList []Submodule
+	// SubmodulesErr makes Submodules fail.
+	SubmodulesErr error
+	// Gitlinks maps "<commit hash> <path>" to the submodule commit recorded
+	// at path in that commit.
+	Gitlinks map[string]string
+	// SubmoduleRepos are returned by OpenSubmodule by path.
+	SubmoduleRepos map[string]*FakeRepository
+	// Worktrees holds the commit checked out in each worktree directory.
+	Worktrees map[string]string
+	// Conflicts lists the files CherryPick reports as conflicting when
+	// picking onto a branch.
+	Conflicts map[string][]string
+
+	head     string
+	branches map[string]string
+	commits  map[string]*fakeCommit
+	order    map[string]int
+	tags     []Tag
+	clock    time.Time
+	// notes holds the notes by notes ref and commit hash
+	notes map[string]map[string]string
+	// shallow holds the commits whose parents are missing in a shallow clone
+	shallow map[string]bool
+}
+
+type fakeCommit struct {
+	Commit
+	files []string
+}
+
+var _ Repository = &FakeRepository{}
+
+// NewFakeRepository returns an empty repository on branch main.
+func NewFakeRepository() *FakeRepository {
+	return &FakeRepository{
+		Responses:      map[string]string{},
+		TagObjects:     map[string]string{},
+		CommitObjects:  map[string]string{},
+		Conflicts:      map[string][]string{},
+		Worktrees:      map[string]string{},
+		Gitlinks:       map[string]string{},
+		SubmoduleRepos: map[string]*FakeRepository{},
+		RemoteBranches: map[string]string{},
+		RemoteNotes:    map[string]map[string]string{},
+		shallow:        map[string]bool{},
+		notes:          map[string]map[string]string{},
+		head:           "main",
+		branches:       map[string]string{},
+		commits:        map[string]*fakeCommit{},
+		order:          map[string]int{},
+		clock:          time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// Commit adds a commit touching files on the current branch and returns its hash.
+func (f *FakeRepository) Commit(message string, files ...string) string {
+	var parents []string
+	if head, ok := f.branches[f.head]; ok {
+		parents = append(parents, head)
+	}
+	return f.addCommit(message, parents, files)
+}
+
+// Merge adds a merge commit of branch into the current branch and returns its hash.
+func (f *FakeRepository) Merge(branch, message string) (string, error) {
+	tip, ok := f.branches[branch]
+	if !ok {
+		return "", fmt.Errorf("fake: unknown branch %s", branch)
+	}
+	return f.addCommit(message, []string{f.branches[f.head], tip}, nil), nil
+}
+
+// Checkout switches to branch, creating it at the current commit if needed.
+func (f *FakeRepository) Checkout(branch string) {
+	if _, ok := f.branches[branch]; !ok {
+		f.branches[branch] = f.branches[f.head]
+	}
+	f.head = branch
+}
+
+// Tag creates a lightweight tag on the current commit.
+func (f *FakeRepository) Tag(name string) error {
+	return f.CreateTag(context.Background(), name, "HEAD", TagOptions{})
+}
+
+// Shallow turns the repository into a clone of depth commits from HEAD, as
+// git clone --depth would.
+func (f *FakeRepository) Shallow(depth int) {
+	f.shallow = f.ancestorsAt(map[string]bool{f.branches[f.head]: true}, depth-1)
+}
+
+// ancestorsAt returns the commits distance parents away from hashes, the
+// boundary of a clone deepened by distance.
+func (f *FakeRepository) ancestorsAt(hashes map[string]bool, distance int) map[string]bool {
+	for ; distance > 0 && len(hashes) > 0; distance-- {
+		next := map[string]bool{}
+		for hash := range hashes {
+			for _, parent := range f.commits[hash].Parents {
+				next[parent] = true
+			}
+		}
+		hashes = next
+	}
+	return hashes
+}
+
+// parents returns the parents of hash visible in the clone.
+func (f *FakeRepository) parents(hash string) []string {
+	if f.shallow[hash] {
+		return nil
+	}
+	return f.commits[hash].Parents
+}
+
+// TagNames returns the names of all tags.
+func (f *FakeRepository) TagNames() []string {
+	var names []string
+	for _, tag := range f.tags {
+		names = append(names, tag.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *FakeRepository) addCommit(message string, parents, files []string) string {
+	f.clock = f.clock.Add(time.Minute)
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d\x00%s\x00%v", len(f.commits), message, parents)))
+	hash := hex.EncodeToString(sum[:])
+	f.commits[hash] = &fakeCommit{
+		Commit: Commit{
+			Hash:        hash,
+			ShortHash:   hash[:7],
+			Parents:     parents,
+			Author:      "Fake Author",
+			AuthorEmail: "fake@example.com",
+			Date:        f.clock,
+			Message:     strings.TrimSpace(message),
+		},
+		files: files,
+	}
+	f.order[hash] = len(f.order)
+	f.branches[f.head] = hash
+	return hash
+}
+
+// resolve turns a ref such as HEAD~2, main, v1.0.0^ or an abbreviated hash
+// into a commit hash.
+func (f *FakeRepository) resolve(ref string) (string, error) {
+	if i := strings.LastIndexAny(ref, "^~"); i > 0 {
+		hash, err := f.resolve(ref[:i])
+		if err != nil {
+			return "", err
+		}
+		n := 1
+		if ref[i+1:] != "" {
+			if n, err = strconv.Atoi(ref[i+1:]); err != nil {
+				return "", fmt.Errorf("fake: invalid ref %s", ref)
+			}
+		}
+		if ref[i] == '^' {
+			// ^n selects the nth parent
+			parents := f.commits[hash].Parents
+			if n == 0 {
+				return hash, nil
+			}
+			if n > len(parents) {
+				return "", fmt.Errorf("fake: %s has no parent %d", ref[:i], n)
+			}
+			return parents[n-1], nil
+		}
+		for ; n > 0; n-- {
+			parents := f.commits[hash].Parents
+			if len(parents) == 0 {
+				return "", fmt.Errorf("fake: invalid ref %s", ref)
+			}
+			hash = parents[0]
+		}
+		return hash, nil
+	}
+
+	ref = strings.TrimPrefix(ref, "refs/heads/")
+	if ref == "HEAD" {
+		ref = f.head
+	}
+	if hash, ok := f.branches[ref]; ok {
+		return hash, nil
+	}
+	name := strings.TrimPrefix(ref, "refs/tags/")
+	for _, tag := range f.tags {
+		if tag.Name == name {
+			return tag.Commit, nil
+		}
+	}
+	if len(ref) >= 4 {
+		var found []string
+		for hash := range f.commits {
+			if strings.HasPrefix(hash, ref) {
+				found = append(found, hash)
+			}
+		}
+		if len(found) == 1 {
+			return found[0], nil
+		}
+	}
+	return "", fmt.Errorf("fake: unknown revision %s", ref)
+}
+
+func (f *FakeRepository) reachable(hash string) map[string]bool {
+	seen := map[string]bool{}
+	stack := []string{hash}
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		stack = append(stack, f.parents(h)...)
+	}
+	return seen
+}
+
+// newestFirst sorts hashes in reverse creation order, like git log.
+func (f *FakeRepository) newestFirst(hashes map[string]bool) []string {
+	var sorted []string
+	for hash := range hashes {
+		sorted = append(sorted, hash)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return f.order[sorted[i]] > f.order[sorted[j]]
+	})
+	return sorted
+}
+
+func (f *FakeRepository) CurrentBranch(_ context.Context) (string, error) {
+	return f.head, nil
+}
+
+func (f *FakeRepository) Branches(_ context.Context) ([]string, error) {
+	var branches []string
+	for branch := range f.branches {
+		if branch != "HEAD" {
+			branches = append(branches, branch)
+		}
+	}
+	sort.Strings(branches)
+	return branches, nil
+}
+
+func (f *FakeRepository) CreateBranch(_ context.Context, name, ref string) error {
+	if _, ok := f.branches[name]; ok {
+		return fmt.Errorf("fake: branch %s already exists", name)
+	}
+	hash, err := f.resolve(ref)
+	if err != nil {
+		return err
+	}
+	f.branches[name] = hash
+	return nil
+}
+
+func (f *FakeRepository) Switch(_ context.Context, branch string) error {
+	if _, ok := f.branches[branch]; !ok {
+		return fmt.Errorf("fake: unknown branch %s", branch)
+	}
+	f.head = branch
+	return nil
+}
+
+func (f *FakeRepository) Detach(_ context.Context, ref string) error {
+	hash, err := f.resolve(ref)
+	if err != nil {
+		return err
+	}
+	f.branches["HEAD"] = hash
+	f.head = "HEAD"
+	return nil
+}
+
+func (f *FakeRepository) CherryPick(_ context.Context, commit string) error {
+	hash, err := f.resolve(commit)
+	if err != nil {
+		return err
+	}
+	if files, ok := f.Conflicts[f.head]; ok {
+		return &CherryPickConflict{Commit: commit, Files: files}
+	}
+	picked := f.commits[hash]
+	message := fmt.Sprintf("%s\n\n(cherry picked from commit %s)", picked.Message, hash)
+	f.addCommit(message, []string{f.branches[f.head]}, picked.files)
+	return nil
+}
+
+func (f *FakeRepository) Tags(_ context.Context, merged string) ([]Tag, error) {
+	var reachable map[string]bool
+	if merged != "" {
+		hash, err := f.resolve(merged)
+		if err != nil {
+			return nil, err
+		}
+		reachable = f.reachable(hash)
+	}
+	var tags []Tag
+	for _, tag := range f.tags {
+		if reachable == nil || reachable[tag.Commit] {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+func (f *FakeRepository) Log(_ context.Context, options LogOptions) ([]Commit, error) {
+	revRange := options.Range
+	if revRange == "" {
+		revRange = "HEAD"
+	}
+	exclude := map[string]bool{}
+	from, to, isRange := strings.Cut(revRange, "..")
+	if !isRange {
+		to = from
+	} else if from != "" {
+		hash, err := f.resolve(from)
+		if err != nil {
+			return nil, err
+		}
+		exclude = f.reachable(hash)
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+	tip, err := f.resolve(to)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := map[string]bool{}
+	if options.FirstParent {
+		for hash := tip; hash != "" && !exclude[hash]; {
+			selected[hash] = true
+			parents := f.parents(hash)
+			hash = ""
+			if len(parents) > 0 {
+				hash = parents[0]
+			}
+		}
+	} else {
+		for hash := range f.reachable(tip) {
+			if !exclude[hash] {
+				selected[hash] = true
+			}
+		}
+	}
+
+	var commits []Commit
+	for _, hash := range f.newestFirst(selected) {
+		commit := f.commits[hash]
+		if options.NoMerges && commit.IsMerge() {
+			continue
+		}
+		if len(options.Paths) > 0 && !commit.touches(options.Paths) {
+			continue
+		}
+		commits = append(commits, commit.Commit)
+		if options.MaxCount > 0 && len(commits) == options.MaxCount {
+			break
+		}
+	}
+	return commits, nil
+}
+
+func (c *fakeCommit) touches(patterns []string) bool {
+	for _, file := range c.files {
+		for _, pattern := range patterns {
+			if matchGlob(pattern, file) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *FakeRepository) IsAncestor(_ context.Context, ancestor, descendant string) (bool, error) {
+	a, err := f.resolve(ancestor)
+	if err != nil {
+		return false, err
+	}
+	d, err := f.resolve(descendant)
+	if err != nil {
+		return false, err
+	}
+	return f.reachable(d)[a], nil
+}
+
+// Diff lists the tracked Dirty entries, standing in for their patch.
+func (f *FakeRepository) Diff(_ context.Context, ref string) (string, error) {
+	if _, err := f.resolve(ref); err != nil {
+		return "", err
+	}
+	var diff strings.Builder
+	for _, entry := range f.Dirty {
+		if !entry.IsUntracked() {
+			fmt.Fprintf(&diff, "%s %s\n", entry.Code, entry.Path)
+		}
+	}
+	return diff.String(), nil
+}
+
+// ChangedFiles returns the files touched by the commits in from..to.
+func (f *FakeRepository) ChangedFiles(_ context.Context, from, to string) ([]string, error) {
+	tip, err := f.resolve(to)
+	if err != nil {
+		return nil, err
+	}
+	exclude := map[string]bool{}
+	if from != EmptyTree {
+		hash, err := f.resolve(from)
+		if err != nil {
+			return nil, err
+		}
+		exclude = f.reachable(hash)
+	}
+	seen := map[string]bool{}
+	var files []string
+	for hash := range f.reachable(tip) {
+		if exclude[hash] {
+			continue
+		}
+		for _, file := range f.commits[hash].files {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (f *FakeRepository) MergeBase(_ context.Context, a, b string) (string, error) {
+	hashA, err := f.resolve(a)
+	if err != nil {
+		return "", err
+	}
+	hashB, err := f.resolve(b)
+	if err != nil {
+		return "", err
+	}
+	fromB := f.reachable(hashB)
+	for _, hash := range f.newestFirst(f.reachable(hashA)) {
+		if fromB[hash] {
+			return hash, nil
+		}
+	}
+	return "", fmt.Errorf("fake: no merge-base of %s and %s", a, b)
+}
+
+func (f *FakeRepository) CreateTag(_ context.Context, name, ref string, options TagOptions) error {
+	hash, err := f.resolve(ref)
+	if err != nil {
+		return err
+	}
+	for _, tag := range f.tags {
+		if tag.Name == name {
+			return fmt.Errorf("fake: tag %s already exists", name)
+		}
+	}
+	if options.IsAnnotated() {
+		tagger := fmt.Sprintf("Fake Tagger <tagger@example.com> %d +0000", f.clock.Unix())
+		payload := tagObject(hash, name, tagger, options.Message)
+		if options.Sign != nil {
+			if payload, err = signTagObject(payload, options.Sign); err != nil {
+				return err
+			}
+		}
+		f.TagObjects[name] = payload
+	}
+	f.tags = append(f.tags, Tag{Name: name, Commit: hash, Date: f.commits[hash].Date, Tagger: "Fake Tagger"})
+	return nil
+}
+
+func (f *FakeRepository) DeleteTag(_ context.Context, name string) error {
+	for i, tag := range f.tags {
+		if tag.Name == name {
+			f.tags = append(f.tags[:i], f.tags[i+1:]...)
+			delete(f.TagObjects, name)
+			return nil
+		}
+	}
+	return fmt.Errorf("fake: tag %s not found", name)
+}
+
+// Fetch updates remote tracking branches for refspecs such as
+// +refs/heads/main:refs/remotes/origin/main, other refspecs are recorded only.
+func (f *FakeRepository) Fetch(_ context.Context, remote string, options FetchOptions, refspecs ...string) error {
+	if options.Deepen > 0 {
+		f.Deepened = append(f.Deepened, options.Deepen)
+		f.shallow = f.ancestorsAt(f.shallow, options.Deepen)
+	}
+	for _, refspec := range refspecs {
+		f.Fetched = append(f.Fetched, refspec)
+		src, dst, _ := strings.Cut(strings.TrimPrefix(refspec, "+"), ":")
+		if notes, ok := f.RemoteNotes[src]; ok && src == dst {
+			f.notes[dst] = maps.Clone(notes)
+			continue
+		}
+		branch, ok := strings.CutPrefix(src, "refs/heads/")
+		if !ok || !strings.HasPrefix(dst, "refs/remotes/") {
+			continue
+		}
+		hash, ok := f.RemoteBranches[branch]
+		if !ok {
+			if hash, ok = f.branches[branch]; !ok {
+				return fmt.Errorf("fake: couldn't find remote ref %s", src)
+			}
+		}
+		f.branches[strings.TrimPrefix(dst, "refs/remotes/")] = hash
+	}
+	return nil
+}
+
+func (f *FakeRepository) IsShallow(_ context.Context) (bool, error) {
+	return len(f.shallow) > 0, nil
+}
+
+func (f *FakeRepository) Push(_ context.Context, remote string, _ PushOptions, refs ...string) error {
+	if f.RejectPushes > 0 {
+		f.RejectPushes--
+		return fmt.Errorf("fake: ! [rejected] %s (fetch first)", strings.Join(refs, " "))
+	}
+	if f.PushErr != nil {
+		return f.PushErr
+	}
+	for _, ref := range refs {
+		f.Pushed = append(f.Pushed, remote+" "+ref)
+	}
+	return nil
+}
+
+func (f *FakeRepository) Status(_ context.Context) ([]StatusEntry, error) {
+	return f.Dirty, nil
+}
+
+func (f *FakeRepository) Describe(_ context.Context, ref string, options DescribeOptions) (string, error) {
+	hash, err := f.resolve(ref)
+	if err != nil {
+		return "", err
+	}
+	fromRef := f.reachable(hash)
+	best, bestDistance := "", -1
+	for _, tag := range f.tags {
+		if options.Match != "" {
+			if matched, _ := path.Match(options.Match, tag.Name); !matched {
+				continue
+			}
+		}
+		if !fromRef[tag.Commit] {
+			continue
+		}
+		distance := 0
+		fromTag := f.reachable(tag.Commit)
+		for h := range fromRef {
+			if !fromTag[h] {
+				distance++
+			}
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = tag.Name, distance
+		}
+	}
+	if best == "" || (options.ExactMatch && bestDistance != 0) {
+		return "", fmt.Errorf("fake: no names found, cannot describe %s", ref)
+	}
+	if bestDistance == 0 && !options.Long {
+		return best, nil
+	}
+	return fmt.Sprintf("%s-%d-g%s", best, bestDistance, hash[:7]), nil
+}
+
+func (f *FakeRepository) Submodules(_ context.Context) ([]Submodule, error) {
+	if f.SubmodulesErr != nil {
+		return nil, f.SubmodulesErr
+	}
+	return f.SubmoduleList, nil
+}
+
+func (f *FakeRepository) SubmoduleCommit(_ context.Context, ref, path string) (string, error) {
+	if ref == EmptyTree {
+		return "", nil
+	}
+	hash, err := f.resolve(ref)
+	if err != nil {
+		return "", err
+	}
+	return f.Gitlinks[hash+" "+path], nil
+}
+
+func (f *FakeRepository) OpenSubmodule(_ context.Context, path string) (Repository, error) {
+	sub, ok := f.SubmoduleRepos[path]
+	if !ok {
+		return nil, fmt.Errorf("fake: no submodule at %s", path)
+	}
+	return sub, nil
+}
+
+func (f *FakeRepository) ReadNote(_ context.Context, notesRef, commit string) (string, error) {
+	hash, err := f.resolve(commit)
+	if err != nil {
+		return "", err
+	}
+	return f.notes[notesRef][hash], nil
+}
+
+func (f *FakeRepository) WriteNote(_ context.Context, notesRef, commit, content string) error {
+	hash, err := f.resolve(commit)
+	if err != nil {
+		return err
+	}
+	if f.notes[notesRef] == nil {
+		f.notes[notesRef] = map[string]string{}
+	}
+	f.notes[notesRef][hash] = content
+	return nil
+}
+
+// AddWorktree creates dir empty and returns a copy of the repository with
+// a detached HEAD at ref.
+func (f *FakeRepository) AddWorktree(_ context.Context, dir, ref string) (Repository, error) {
+	hash, err := f.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f.Worktrees[dir] = hash
+	worktree := *f
+	worktree.branches = maps.Clone(f.branches)
+	worktree.branches["HEAD"] = hash
+	worktree.head = "HEAD"
+	worktree.Dirty = nil
+	return &worktree, nil
+}
+
+func (f *FakeRepository) RemoveWorktree(_ context.Context, dir string) error {
+	if _, ok := f.Worktrees[dir]; !ok {
+		return fmt.Errorf("fake: %s is not a worktree", dir)
+	}
+	delete(f.Worktrees, dir)
+	return os.RemoveAll(dir)
+}
+
+func (f *FakeRepository) CatFile(_ context.Context, ref string) (string, string, error) {
+	if object, ok := f.TagObjects[strings.TrimPrefix(ref, "refs/tags/")]; ok {
+		return "tag", object, nil
+	}
+	hash, err := f.resolve(ref)
+	if err != nil {
+		return "", "", err
+	}
+	if object, ok := f.CommitObjects[hash]; ok {
+		return "commit", object, nil
+	}
+	commit := f.commits[hash]
+	sb := strings.Builder{}
+	sb.WriteString("tree " + EmptyTree + "\n")
+	for _, parent := range commit.Parents {
+		fmt.Fprintf(&sb, "parent %s\n", parent)
+	}
+	ident := fmt.Sprintf("%s <%s> %d +0000", commit.Author, commit.AuthorEmail, commit.Date.Unix())
+	fmt.Fprintf(&sb, "author %s\ncommitter %s\n\n%s\n", ident, ident, commit.Message)
+	return "commit", sb.String(), nil
+}
+
+func (f *FakeRepository) Run(_ context.Context, args ...string) (string, error) {
+	key := strings.Join(args, " ")
+	f.Calls = append(f.Calls, key)
+	out, ok := f.Responses[key]
+	if !ok {
+		return "", fmt.Errorf("fake: unexpected git %s", key)
+	}
+	return out, nil
+}
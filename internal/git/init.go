@@ -1,6 +1,11 @@
 package git
 
-import "github.com/davidjspooner/cicd-utilities/pkg/command"
+import (
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/command"
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
 
 func Commands() []command.Command {
 
@@ -8,25 +13,172 @@ func Commands() []command.Command {
 		"git",
 		"Git commands",
 		nil,
-		&command.NoopOptions{},
+		&GitOptions{},
 	)
 
 	cmd1 := command.NewCommand(
 		"suggest-build-env",
 		"Get the environment variables for the current build",
 		executeGetGitEnv,
-		&GetGitEnvOptions{},
+		&GetGitEnvOptions{
+			Format: "dotenv",
+			Prefix: defaultEnvPrefix,
+		},
 	)
 	cmd2 := command.NewCommand(
 		"update-tag",
 		"Automatically increment Git tags based on commit messages (e.g., fix:, feat:, breaking:)",
 		executeBumpGitTag,
 		&BumpGitTagOptions{
-			Remote: "origin",
-			Prefix: "v",
+			Remote:     defaultRemote,
+			Scheme:     "semver",
+			Format:     semantic.DefaultCalVerFormat,
+			FetchDepth: 1000,
+
+			ReleaseBranchPrefix: defaultReleaseBranchPrefix,
+			ReleaseBranchPolicy: "refuse",
+		},
+	)
+
+	cmd3 := command.NewCommand(
+		"changelog",
+		"Generate release notes from conventional commits",
+		executeChangelog,
+		&ChangelogOptions{
+			To:     "HEAD",
+			Format: "markdown",
+		},
+	)
+
+	cmd4 := command.NewCommand(
+		"lint-commits",
+		"Check commit messages against the conventional commits grammar",
+		executeLintCommits,
+		&LintCommitsOptions{
+			Types:           strings.Join(semantic.DefaultCommitTypes, ","),
+			MaxHeaderLength: 72,
+		},
+	)
+
+	cmd5 := command.NewCommand(
+		"verify-tag",
+		"Verify the OpenPGP signature of tags against a keyring of release keys",
+		executeVerifyTag,
+		&VerifyOptions{},
+	)
+
+	cmd6 := command.NewCommand(
+		"verify-commits",
+		"Verify the OpenPGP signatures of the commits in a revision range",
+		executeVerifyCommits,
+		&VerifyOptions{},
+	)
+
+	cmd7 := command.NewCommand(
+		"changed",
+		"List the files and components changed between two refs",
+		executeChanged,
+		&ChangedOptions{
+			Head:   "HEAD",
+			Format: "json",
+		},
+	)
+
+	cmd8 := command.NewCommand(
+		"backport",
+		"Cherry-pick commits onto a new branch for each release branch",
+		executeBackport,
+		&BackportOptions{
+			BranchPrefix: "backport/",
+			Remote:       defaultRemote,
 		},
 	)
 
-	gitCommand.SubCommands().MustAdd(cmd1, cmd2)
+	cmd9 := command.NewCommand(
+		"tags",
+		"List version tags in version order with the commits between them",
+		executeTags,
+		&TagsOptions{Format: "table"},
+	)
+
+	cmd10 := command.NewCommand(
+		"stats",
+		"Report commits per type, contributors and lines changed in a range (default: since the latest tag, or the one before it when HEAD is tagged)",
+		executeStats,
+		&StatsOptions{Format: "table"},
+	)
+
+	cmd11 := command.NewCommand(
+		"preflight",
+		"Run repository hygiene checks before tagging a release",
+		executePreflight,
+		&PreflightOptions{
+			Remote:      defaultRemote,
+			MaxFileSize: 5120,
+		},
+	)
+
+	cmd12 := command.NewCommand(
+		"worktree-exec",
+		"Run a command in a temporary worktree at a ref with the build variables set",
+		executeWorktreeExec,
+		&WorktreeExecOptions{Prefix: defaultEnvPrefix},
+	)
+
+	releaseBranchCommand := command.NewCommand(
+		"release-branch",
+		"Create and list release/X.Y maintenance branches",
+		nil,
+		&command.NoopOptions{},
+	)
+	releaseBranchCommand.SubCommands().MustAdd(
+		command.NewCommand(
+			"create",
+			"Create the release branch for the minor version of a tag (default: the latest tag)",
+			executeReleaseBranchCreate,
+			&ReleaseBranchOptions{Prefix: defaultReleaseBranchPrefix, Remote: defaultRemote},
+		),
+		command.NewCommand(
+			"list",
+			"List release branches with their latest tag",
+			executeReleaseBranchList,
+			&ReleaseBranchOptions{Prefix: defaultReleaseBranchPrefix, Remote: defaultRemote},
+		),
+	)
+
+	notesCommand := command.NewCommand(
+		"notes",
+		"Record and read build provenance stored as JSON in git notes",
+		nil,
+		&command.NoopOptions{},
+	)
+	notesCommand.SubCommands().MustAdd(
+		command.NewCommand(
+			"add",
+			"Add a build record with the CI run, checksums and metadata to a commit (default: HEAD)",
+			executeNotesAdd,
+			&NotesAddOptions{Ref: defaultNotesRef, Remote: defaultRemote},
+		),
+		command.NewCommand(
+			"show",
+			"Print the build records of a commit (default: HEAD)",
+			executeNotesShow,
+			&NotesOptions{Ref: defaultNotesRef, Remote: defaultRemote},
+		),
+		command.NewCommand(
+			"push",
+			"Push the notes ref",
+			executeNotesPush,
+			&NotesOptions{Ref: defaultNotesRef, Remote: defaultRemote},
+		),
+		command.NewCommand(
+			"fetch",
+			"Fetch the notes ref",
+			executeNotesFetch,
+			&NotesOptions{Ref: defaultNotesRef, Remote: defaultRemote},
+		),
+	)
+
+	gitCommand.SubCommands().MustAdd(cmd1, cmd2, cmd3, cmd4, cmd5, cmd6, cmd7, cmd8, cmd9, cmd10, cmd11, cmd12, releaseBranchCommand, notesCommand)
 	return []command.Command{gitCommand}
 }
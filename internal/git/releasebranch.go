@@ -0,0 +1,83 @@
+package git
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/ci"
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+// releaseBranchName returns the branch that maintains the minor version
+// line of v, e.g. release/1.4.
+func releaseBranchName(prefix string, v semantic.Version) string {
+	return fmt.Sprintf("%s%d.%d", prefix, v.Major, v.Minor)
+}
+
+// parseReleaseBranch returns the version line of a release branch. ok is
+// false for branches that are not named prefix<major>.<minor>.
+func parseReleaseBranch(prefix, branch string) (line semantic.Version, ok bool) {
+	name, found := strings.CutPrefix(branch, prefix)
+	if !found || prefix == "" {
+		return line, false
+	}
+	major, minor, found := strings.Cut(name, ".")
+	if !found {
+		return line, false
+	}
+	var err error
+	if line.Major, err = strconv.Atoi(major); err != nil {
+		return line, false
+	}
+	if line.Minor, err = strconv.Atoi(minor); err != nil {
+		return line, false
+	}
+	return line, true
+}
+
+// resolveReleaseBranch returns the branch being released. CI systems
+// usually check out a detached HEAD, then the branch comes from build.
+func resolveReleaseBranch(branch string, build ci.Build) (string, error) {
+	if branch != "HEAD" {
+		return branch, nil
+	}
+	if build.Branch != "" {
+		return build.Branch, nil
+	}
+	return "", fmt.Errorf("HEAD is detached and no CI branch was found")
+}
+
+// releaseBranchBump enforces that release branches only get patch releases.
+// With the patch policy larger bumps are turned into a patch, otherwise
+// they are refused.
+func releaseBranchBump(prefix, policy, branch, bump string) (string, error) {
+	if _, ok := parseReleaseBranch(prefix, branch); !ok || bump == "patch" {
+		return bump, nil
+	}
+	switch policy {
+	case "patch":
+		slog.Info("Release branch only gets patch releases", "branch", branch, "bump", bump)
+		return "patch", nil
+	case "refuse", "":
+		return "", fmt.Errorf("%s only gets patch releases but the commits need a %s bump, "+
+			"use --release-branch-policy patch to release them as a patch", branch, bump)
+	default:
+		return "", fmt.Errorf("unknown release branch policy %q, expected refuse or patch", policy)
+	}
+}
+
+// checkReleaseLine makes sure a version released on a release branch
+// belongs to the line the branch is named after.
+func checkReleaseLine(prefix, branch string, version semantic.Versioned) error {
+	line, ok := parseReleaseBranch(prefix, branch)
+	if !ok {
+		return nil
+	}
+	v, ok := version.(semantic.Version)
+	if !ok || v.Major != line.Major || v.Minor != line.Minor {
+		return fmt.Errorf("version %s does not belong on %s", version, branch)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+// fetchRemoteState fetches the tags and branch from remote and makes sure
+// HEAD has been pushed, so that tags are only created on published commits.
+func fetchRemoteState(ctx context.Context, repo Repository, remote, branch string) error {
+	refspecs := []string{"refs/tags/*:refs/tags/*"}
+	if branch != "HEAD" {
+		refspecs = append(refspecs, fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, remote, branch))
+	}
+	if err := repo.Fetch(ctx, remote, FetchOptions{}, refspecs...); err != nil {
+		return err
+	}
+	if branch == "HEAD" {
+		slog.Warn("Detached HEAD, not checking that it is pushed", "remote", remote)
+		return nil
+	}
+	remoteBranch := remote + "/" + branch
+	pushed, err := repo.IsAncestor(ctx, "HEAD", remoteBranch)
+	if err != nil {
+		return err
+	}
+	if !pushed {
+		return fmt.Errorf("HEAD is not on %s, push or rebase the branch before tagging", remoteBranch)
+	}
+	return nil
+}
+
+// checkNewTags fails when a planned tag already exists, or when the remote
+// branch has a newer release than the latest tag the plan starts from.
+func checkNewTags(ctx context.Context, repo Repository, scheme semantic.Scheme, remote, branch string, plans []*tagPlan) error {
+	tags, err := repo.Tags(ctx, "")
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, tag := range tags {
+		existing[tag.Name] = true
+	}
+	for _, plan := range plans {
+		if branch != "HEAD" && plan.Strategy != "from-tag" {
+			remoteTag, err := GetLatestTag(ctx, scheme, remote+"/"+branch, plan.TagFilter)
+			if err == nil && remoteTag != plan.LatestTag {
+				_, _, remoteVersion, err := scheme.ExtractVersionFromTag(strings.TrimPrefix(remoteTag, plan.TagFilter))
+				if err == nil && (plan.Current == nil || remoteVersion.CompareTo(plan.Current) > 0) {
+					return fmt.Errorf("%s/%s already has the newer release %s, update your checkout", remote, branch, remoteTag)
+				}
+			}
+		}
+		if existing[plan.NewTag] {
+			return fmt.Errorf("tag %s already exists, is HEAD behind %s/%s?", plan.NewTag, remote, branch)
+		}
+	}
+	return nil
+}
+
+// pushTags pushes refs, atomically when there is more than one. Tags that
+// did not reach the remote are deleted again so that a retry starts clean.
+func pushTags(ctx context.Context, repo Repository, remote string, refs, created []string) error {
+	err := repo.Push(ctx, remote, PushOptions{Atomic: len(refs) > 1}, refs...)
+	if err == nil {
+		return nil
+	}
+	deleteTags(ctx, repo, created)
+	return fmt.Errorf("failed to push tag: %v", err)
+}
+
+// isPushRejected reports whether a push failed because the remote ref has
+// commits the local one does not, or was updated while pushing.
+func isPushRejected(err error) bool {
+	for _, reason := range []string{"non-fast-forward", "fetch first", "cannot lock ref"} {
+		if strings.Contains(err.Error(), reason) {
+			return true
+		}
+	}
+	return false
+}
+
+func deleteTags(ctx context.Context, repo Repository, tags []string) {
+	for _, tag := range tags {
+		if err := repo.DeleteTag(ctx, tag); err != nil {
+			slog.Warn("Failed to delete local tag", "tag", tag, "error", err)
+			continue
+		}
+		slog.Info("Deleted local tag", "tag", tag)
+	}
+}
+
+// deepenStep is the number of commits a shallow clone is deepened by at a
+// time, doubled on every round.
+const deepenStep = 50
+
+// deepenUntil fetches more history into a shallow clone until found reports
+// true, the clone is complete, or maxDepth commits have been fetched.
+func deepenUntil(ctx context.Context, repo Repository, remote string, maxDepth int, found func() bool) error {
+	shallow, err := repo.IsShallow(ctx)
+	if err != nil {
+		return err
+	}
+	if !shallow || found() {
+		return nil
+	}
+	step, fetched := deepenStep, 0
+	for fetched < maxDepth && shallow {
+		step = min(step, maxDepth-fetched)
+		slog.Info("Shallow clone, fetching more history", "remote", remote, "deepen", step)
+		if err := repo.Fetch(ctx, remote, FetchOptions{Tags: true, Deepen: step}); err != nil {
+			return err
+		}
+		if found() {
+			return nil
+		}
+		fetched += step
+		step *= 2
+		if shallow, err = repo.IsShallow(ctx); err != nil {
+			return err
+		}
+	}
+	if !shallow {
+		// complete history, let the caller report what is missing
+		return nil
+	}
+	return fmt.Errorf("no version tag found in this shallow clone after fetching %d more commits, "+
+		"check out the full history (e.g. fetch-depth: 0 with actions/checkout) or raise --fetch-depth", fetched)
+}
@@ -0,0 +1,224 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/command"
+)
+
+// Repository is the set of git operations used by the commands. The exec
+// implementation runs the git binary, the fake keeps a scripted history in
+// memory so commands can be unit tested.
+type Repository interface {
+	CurrentBranch(ctx context.Context) (string, error)
+	// Branches lists local branches and remote tracking branches such as
+	// origin/main.
+	Branches(ctx context.Context) ([]string, error)
+	CreateBranch(ctx context.Context, name, ref string) error
+	// Switch checks out an existing branch.
+	Switch(ctx context.Context, branch string) error
+	// Detach checks out ref with a detached HEAD.
+	Detach(ctx context.Context, ref string) error
+	// CherryPick applies commit to the current branch, recording where it
+	// came from like git cherry-pick -x. A conflicting pick is aborted and
+	// returned as a *CherryPickConflict.
+	CherryPick(ctx context.Context, commit string) error
+	// Tags lists tags, only those reachable from merged when it is not empty.
+	Tags(ctx context.Context, merged string) ([]Tag, error)
+	Log(ctx context.Context, options LogOptions) ([]Commit, error)
+	IsAncestor(ctx context.Context, ancestor, descendant string) (bool, error)
+	// Diff returns the binary patch from ref to the work tree, untracked
+	// files excluded.
+	Diff(ctx context.Context, ref string) (string, error)
+	// ChangedFiles lists the files that differ between two commits.
+	ChangedFiles(ctx context.Context, from, to string) ([]string, error)
+	MergeBase(ctx context.Context, a, b string) (string, error)
+	CreateTag(ctx context.Context, name, ref string, options TagOptions) error
+	DeleteTag(ctx context.Context, name string) error
+	Fetch(ctx context.Context, remote string, options FetchOptions, refspecs ...string) error
+	IsShallow(ctx context.Context) (bool, error)
+	Push(ctx context.Context, remote string, options PushOptions, refs ...string) error
+	Status(ctx context.Context) ([]StatusEntry, error)
+	Describe(ctx context.Context, ref string, options DescribeOptions) (string, error)
+	// Submodules lists the submodules, recursively, with their state.
+	Submodules(ctx context.Context) ([]Submodule, error)
+	// SubmoduleCommit returns the commit ref records for the submodule at
+	// path, or "" when there is no submodule there.
+	SubmoduleCommit(ctx context.Context, ref, path string) (string, error)
+	// OpenSubmodule returns the repository checked out at path.
+	OpenSubmodule(ctx context.Context, path string) (Repository, error)
+	// ReadNote returns the note on commit in the notes ref, or "" when the
+	// commit has none.
+	ReadNote(ctx context.Context, notesRef, commit string) (string, error)
+	// WriteNote replaces the note on commit in the notes ref.
+	WriteNote(ctx context.Context, notesRef, commit, content string) error
+	// AddWorktree checks out ref, detached, in a new worktree at dir and
+	// returns the repository as seen from there.
+	AddWorktree(ctx context.Context, dir, ref string) (Repository, error)
+	// RemoveWorktree deletes a worktree made by AddWorktree, changes and all.
+	RemoveWorktree(ctx context.Context, dir string) error
+	// CatFile returns the type and the raw content of the object ref names.
+	CatFile(ctx context.Context, ref string) (objectType, content string, err error)
+	// Run is the escape hatch for operations without a dedicated method.
+	Run(ctx context.Context, args ...string) (string, error)
+}
+
+// Commit is a single entry from git log.
+type Commit struct {
+	Hash        string
+	ShortHash   string
+	Parents     []string
+	Author      string
+	AuthorEmail string
+	Date        time.Time
+	Message     string
+}
+
+// Subject returns the first line of the commit message.
+func (c Commit) Subject() string {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	return strings.TrimSpace(subject)
+}
+
+// IsMerge reports whether the commit has more than one parent.
+func (c Commit) IsMerge() bool {
+	return len(c.Parents) > 1
+}
+
+// Tag is a tag with the commit it points at.
+type Tag struct {
+	Name   string
+	Commit string
+	// Date is the commit date of Commit, not when the tag was made.
+	Date   time.Time
+	Tagger string
+}
+
+// LogOptions select the commits returned by Log.
+type LogOptions struct {
+	// Range is a revision range such as "v1.0.0..HEAD" or a single ref.
+	Range       string
+	Paths       []string
+	FirstParent bool
+	NoMerges    bool
+	MaxCount    int
+}
+
+// TagOptions turn a lightweight tag into an annotated one.
+type TagOptions struct {
+	// Message makes the tag annotated when it is not empty.
+	Message string
+	// Sign returns an armored detached signature of the tag object. The
+	// signature is appended to the tag the same way git tag -s does.
+	Sign func(payload []byte) (string, error)
+}
+
+// IsAnnotated reports whether a tag object has to be written.
+func (o TagOptions) IsAnnotated() bool {
+	return o.Message != "" || o.Sign != nil
+}
+
+// tagObject returns the content of a tag object without its signature.
+func tagObject(commit, name, tagger, message string) string {
+	return fmt.Sprintf("object %s\ntype commit\ntag %s\ntagger %s\n\n%s\n", commit, name, tagger, strings.TrimSpace(message))
+}
+
+// signTagObject appends the signature made by sign to payload.
+func signTagObject(payload string, sign func(payload []byte) (string, error)) (string, error) {
+	signature, err := sign([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign tag: %v", err)
+	}
+	if !strings.HasSuffix(signature, "\n") {
+		signature += "\n"
+	}
+	return payload + signature, nil
+}
+
+// CherryPickConflict is returned by CherryPick when commit does not apply.
+type CherryPickConflict struct {
+	Commit string
+	Files  []string
+}
+
+func (e *CherryPickConflict) Error() string {
+	return fmt.Sprintf("cherry-pick of %s conflicts in %s", e.Commit, strings.Join(e.Files, ", "))
+}
+
+// FetchOptions control Fetch.
+type FetchOptions struct {
+	Tags bool
+	// Deepen extends the history of a shallow clone by this many commits.
+	Deepen int
+}
+
+// PushOptions control Push.
+type PushOptions struct {
+	// Atomic makes the remote accept either all refs or none.
+	Atomic bool
+}
+
+// StatusEntry is one line of git status --porcelain.
+type StatusEntry struct {
+	Code string
+	Path string
+}
+
+// IsUntracked reports whether the path is not tracked by git.
+func (e StatusEntry) IsUntracked() bool {
+	return e.Code == "??"
+}
+
+// Submodule is one entry of git submodule status.
+type Submodule struct {
+	// Path is relative to the top of the superproject.
+	Path string
+	// Commit is the commit checked out, or the recorded one when the
+	// submodule is not initialized.
+	Commit      string
+	Initialized bool
+	// OutOfDate is set when the checked out commit is not the one the
+	// superproject records.
+	OutOfDate bool
+	Conflict  bool
+	// Dirty is set when the submodule has uncommitted changes.
+	Dirty bool
+}
+
+// DescribeOptions control Describe, which always considers lightweight tags.
+type DescribeOptions struct {
+	Match      string
+	ExactMatch bool
+	Long       bool
+}
+
+// EmptyTree is the hash of the empty tree, a base that every file differs from.
+const EmptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+type repositoryKey struct{}
+
+// WithRepository returns a context whose commands use repo.
+func WithRepository(ctx context.Context, repo Repository) context.Context {
+	return context.WithValue(ctx, repositoryKey{}, repo)
+}
+
+// RepositoryFromContext returns the repository set with WithRepository, or
+// an exec repository in the directory given to the git command.
+func RepositoryFromContext(ctx context.Context) Repository {
+	if repo, ok := ctx.Value(repositoryKey{}).(Repository); ok {
+		return repo
+	}
+	dir := ""
+	if options, err := command.FindOptionStruct[GitOptions](ctx); err == nil {
+		dir = options.Dir
+	}
+	return NewExecRepository(dir)
+}
+
+// GitOptions are the flags shared by all git subcommands.
+type GitOptions struct {
+	Dir string `flag:"--repo-dir,Directory of the git repository (default: current directory)"`
+}
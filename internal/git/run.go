@@ -2,19 +2,519 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// Run runs git in the current directory and returns its trimmed output.
 func Run(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	var out bytes.Buffer
+	return NewExecRepository("").Run(context.Background(), args...)
+}
+
+// ExecRepository runs the git binary against the repository in Dir.
+type ExecRepository struct {
+	Dir string
+	// Env holds extra KEY=VALUE pairs added to the environment of git.
+	Env []string
+}
+
+var _ Repository = &ExecRepository{}
+
+func NewExecRepository(dir string) *ExecRepository {
+	return &ExecRepository{Dir: dir}
+}
+
+// inheritedGitEnv are the GIT_* variables passed through to git. Everything
+// else, such as GIT_DIR or GIT_INDEX_FILE set by a hook, would point git at
+// another repository than Dir.
+var inheritedGitEnv = []string{
+	"GIT_ASKPASS",
+	"GIT_AUTHOR_DATE",
+	"GIT_AUTHOR_EMAIL",
+	"GIT_AUTHOR_NAME",
+	"GIT_COMMITTER_DATE",
+	"GIT_COMMITTER_EMAIL",
+	"GIT_COMMITTER_NAME",
+	"GIT_HTTP_USER_AGENT",
+	"GIT_SSH",
+	"GIT_SSH_COMMAND",
+	"GIT_SSL_CAINFO",
+	"GIT_SSL_NO_VERIFY",
+	"GIT_TERMINAL_PROMPT",
+	"GIT_TRACE",
+}
+
+func (r *ExecRepository) environ() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "GIT_") && !isInheritedGitEnv(name) {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return append(env, r.Env...)
+}
+
+func isInheritedGitEnv(name string) bool {
+	for _, allowed := range inheritedGitEnv {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// output runs git and returns stdout untouched. Failures include what git
+// wrote to stderr.
+func (r *ExecRepository) output(ctx context.Context, stdin io.Reader, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.Dir
+	cmd.Env = r.environ()
+	cmd.Stdin = stdin
+	var out, stderr bytes.Buffer
 	cmd.Stdout = &out
-	cmd.Stderr = os.Stderr
+	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("git %v failed: %v", args, err)
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return "", fmt.Errorf("git %v failed: %v", args, err)
+		}
+		return "", fmt.Errorf("git %v failed: %v: %s", args, err, msg)
+	}
+	return out.String(), nil
+}
+
+func (r *ExecRepository) Run(ctx context.Context, args ...string) (string, error) {
+	out, err := r.output(ctx, nil, args...)
+	return strings.TrimSpace(out), err
+}
+
+// runUntranslated is Run with git's messages left in English, for callers
+// that look for a message in the error.
+func (r *ExecRepository) runUntranslated(ctx context.Context, args ...string) (string, error) {
+	c := *r
+	c.Env = append(slices.Clone(r.Env), "LC_ALL=C")
+	return c.Run(ctx, args...)
+}
+
+func (r *ExecRepository) CurrentBranch(ctx context.Context) (string, error) {
+	branch, err := r.Run(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %v", err)
+	}
+	return branch, nil
+}
+
+func (r *ExecRepository) Branches(ctx context.Context) ([]string, error) {
+	out, err := r.Run(ctx, "for-each-ref", "--format=%(refname)", "refs/heads", "refs/remotes")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %v", err)
+	}
+	var branches []string
+	for _, ref := range splitLines(out) {
+		if ref == "" || strings.HasSuffix(ref, "/HEAD") {
+			continue
+		}
+		if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+			branches = append(branches, branch)
+		} else {
+			branches = append(branches, strings.TrimPrefix(ref, "refs/remotes/"))
+		}
+	}
+	return branches, nil
+}
+
+func (r *ExecRepository) CreateBranch(ctx context.Context, name, ref string) error {
+	if _, err := r.Run(ctx, "branch", name, ref); err != nil {
+		return fmt.Errorf("failed to create branch %s: %v", name, err)
+	}
+	return nil
+}
+
+func (r *ExecRepository) Switch(ctx context.Context, branch string) error {
+	if _, err := r.Run(ctx, "checkout", branch); err != nil {
+		return fmt.Errorf("failed to switch to %s: %v", branch, err)
+	}
+	return nil
+}
+
+func (r *ExecRepository) Detach(ctx context.Context, ref string) error {
+	if _, err := r.Run(ctx, "checkout", "--detach", ref); err != nil {
+		return fmt.Errorf("failed to check out %s: %v", ref, err)
+	}
+	return nil
+}
+
+func (r *ExecRepository) CherryPick(ctx context.Context, commit string) error {
+	_, err := r.Run(ctx, "cherry-pick", "-x", commit)
+	if err == nil {
+		return nil
+	}
+	out, _ := r.Run(ctx, "diff", "--name-only", "--diff-filter=U")
+	// leave the work tree as it was before the pick
+	if _, abortErr := r.Run(ctx, "cherry-pick", "--abort"); abortErr != nil {
+		return fmt.Errorf("failed to cherry-pick %s: %v, and the abort failed: %v", commit, err, abortErr)
+	}
+	if files := splitLines(out); len(files) > 0 {
+		return &CherryPickConflict{Commit: commit, Files: files}
+	}
+	return fmt.Errorf("failed to cherry-pick %s: %v", commit, err)
+}
+
+const tagFormat = "--format=%(refname:short)%1f%(objectname)%1f%(*objectname)%1f%(if)%(*objectname)%(then)%(*committerdate:iso-strict)%(else)%(committerdate:iso-strict)%(end)%1f%(if)%(taggername)%(then)%(taggername)%(else)%(authorname)%(end)"
+
+func (r *ExecRepository) Tags(ctx context.Context, merged string) ([]Tag, error) {
+	args := []string{"for-each-ref", tagFormat}
+	if merged != "" {
+		args = append(args, "--merged", merged)
+	}
+	args = append(args, "refs/tags")
+	out, err := r.Run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %v", err)
+	}
+	var tags []Tag
+	for _, line := range splitLines(out) {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected tag record %q", line)
+		}
+		tag := Tag{Name: fields[0], Commit: fields[1], Tagger: fields[4]}
+		if fields[2] != "" {
+			// annotated tag, use the commit it points at
+			tag.Commit = fields[2]
+		}
+		tag.Date, _ = time.Parse(time.RFC3339, fields[3])
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+const logFormat = "--format=%H%x1f%h%x1f%P%x1f%an%x1f%ae%x1f%cI%x1f%B%x1e"
+
+func (r *ExecRepository) Log(ctx context.Context, options LogOptions) ([]Commit, error) {
+	args := []string{"log", logFormat}
+	if options.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	if options.NoMerges {
+		args = append(args, "--no-merges")
+	}
+	if options.MaxCount > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(options.MaxCount))
+	}
+	args = append(args, options.Range)
+	if len(options.Paths) > 0 {
+		args = append(append(args, "--"), pathspecs(options.Paths)...)
+	}
+	out, err := r.Run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits for %s: %v", options.Range, err)
+	}
+	return parseLog(out)
+}
+
+func parseLog(out string) ([]Commit, error) {
+	var commits []Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x1f", 7)
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("unexpected git log record %q", record)
+		}
+		date, err := time.Parse(time.RFC3339, fields[5])
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit date %q: %v", fields[5], err)
+		}
+		commits = append(commits, Commit{
+			Hash:        fields[0],
+			ShortHash:   fields[1],
+			Parents:     strings.Fields(fields[2]),
+			Author:      fields[3],
+			AuthorEmail: fields[4],
+			Date:        date,
+			Message:     strings.TrimSpace(fields[6]),
+		})
+	}
+	return commits, nil
+}
+
+func (r *ExecRepository) IsAncestor(ctx context.Context, ancestor, descendant string) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "merge-base", "--is-ancestor", ancestor, descendant)
+	cmd.Dir = r.Dir
+	cmd.Env = r.environ()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("git merge-base --is-ancestor %s %s failed: %v: %s", ancestor, descendant, err, strings.TrimSpace(stderr.String()))
+	}
+	return true, nil
+}
+
+func (r *ExecRepository) Diff(ctx context.Context, ref string) (string, error) {
+	// fixed prefixes so that diff.noprefix and diff.mnemonicPrefix do not
+	// change the patch
+	out, err := r.output(ctx, nil, "diff", "--binary", "--no-color", "--no-ext-diff", "--no-renames",
+		"--src-prefix=a/", "--dst-prefix=b/", ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff the work tree against %s: %v", ref, err)
+	}
+	return out, nil
+}
+
+func (r *ExecRepository) ChangedFiles(ctx context.Context, from, to string) ([]string, error) {
+	out, err := r.output(ctx, nil, "diff", "--name-only", "--no-renames", "-z", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s and %s: %v", from, to, err)
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func (r *ExecRepository) MergeBase(ctx context.Context, a, b string) (string, error) {
+	base, err := r.Run(ctx, "merge-base", a, b)
+	if err != nil {
+		return "", fmt.Errorf("failed to find merge-base of %s and %s: %v", a, b, err)
+	}
+	return base, nil
+}
+
+func (r *ExecRepository) CreateTag(ctx context.Context, name, ref string, options TagOptions) error {
+	if !options.IsAnnotated() {
+		if _, err := r.Run(ctx, "tag", name, ref); err != nil {
+			return fmt.Errorf("failed to create tag %s: %v", name, err)
+		}
+		return nil
+	}
+
+	// Write the tag object ourselves so that it can be signed without gpg.
+	commit, err := r.Run(ctx, "rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %v", ref, err)
+	}
+	tagger, err := r.Run(ctx, "var", "GIT_COMMITTER_IDENT")
+	if err != nil {
+		return fmt.Errorf("failed to get tagger identity: %v", err)
+	}
+	payload := tagObject(commit, name, tagger, options.Message)
+	if options.Sign != nil {
+		if payload, err = signTagObject(payload, options.Sign); err != nil {
+			return err
+		}
+	}
+	object, err := r.output(ctx, strings.NewReader(payload), "mktag")
+	if err != nil {
+		return fmt.Errorf("failed to create tag %s: %v", name, err)
+	}
+	// an empty old value makes update-ref fail if the tag already exists
+	if _, err := r.Run(ctx, "update-ref", "refs/tags/"+name, strings.TrimSpace(object), ""); err != nil {
+		return fmt.Errorf("failed to create tag %s: %v", name, err)
+	}
+	return nil
+}
+
+func (r *ExecRepository) DeleteTag(ctx context.Context, name string) error {
+	if _, err := r.Run(ctx, "tag", "--delete", name); err != nil {
+		return fmt.Errorf("failed to delete tag %s: %v", name, err)
+	}
+	return nil
+}
+
+func (r *ExecRepository) Fetch(ctx context.Context, remote string, options FetchOptions, refspecs ...string) error {
+	args := []string{"fetch", "--quiet"}
+	if options.Tags {
+		args = append(args, "--tags")
+	}
+	if options.Deepen > 0 {
+		args = append(args, "--deepen="+strconv.Itoa(options.Deepen))
+	}
+	args = append(append(args, remote), refspecs...)
+	// fetchNotes looks for "couldn't find remote ref"
+	if _, err := r.runUntranslated(ctx, args...); err != nil {
+		return fmt.Errorf("failed to fetch from %s: %v", remote, err)
+	}
+	return nil
+}
+
+func (r *ExecRepository) IsShallow(ctx context.Context) (bool, error) {
+	out, err := r.Run(ctx, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return false, err
+	}
+	return out == "true", nil
+}
+
+func (r *ExecRepository) Push(ctx context.Context, remote string, options PushOptions, refs ...string) error {
+	args := []string{"push"}
+	if options.Atomic {
+		args = append(args, "--atomic")
+	}
+	args = append(append(args, remote), refs...)
+	// isPushRejected looks for the reason git gives
+	if _, err := r.runUntranslated(ctx, args...); err != nil {
+		return fmt.Errorf("failed to push to %s: %v", remote, err)
+	}
+	return nil
+}
+
+func (r *ExecRepository) Status(ctx context.Context) ([]StatusEntry, error) {
+	out, err := r.output(ctx, nil, "status", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %v", err)
+	}
+	var entries []StatusEntry
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		entries = append(entries, StatusEntry{Code: line[:2], Path: line[3:]})
+	}
+	return entries, nil
+}
+
+func (r *ExecRepository) Describe(ctx context.Context, ref string, options DescribeOptions) (string, error) {
+	args := []string{"describe", "--tags"}
+	if options.Match != "" {
+		args = append(args, "--match", options.Match)
+	}
+	if options.ExactMatch {
+		args = append(args, "--exact-match")
+	}
+	if options.Long {
+		args = append(args, "--long")
+	}
+	return r.Run(ctx, append(args, ref)...)
+}
+
+func (r *ExecRepository) Submodules(ctx context.Context) ([]Submodule, error) {
+	root, err := r.Run(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	// not trimmed, the first column is the state of the first submodule
+	out, err := r.output(ctx, nil, "-C", root, "submodule", "status", "--recursive")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submodule status: %v", err)
+	}
+	var submodules []Submodule
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		// e.g. "+3f2a9c1... libs/parser (v1.2.0-3-g3f2a9c1)"
+		state := line[0]
+		commit, path, _ := strings.Cut(line[1:], " ")
+		if i := strings.LastIndex(path, " ("); i >= 0 {
+			path = path[:i]
+		}
+		submodule := Submodule{
+			Path:        path,
+			Commit:      commit,
+			Initialized: state != '-',
+			OutOfDate:   state == '+',
+			Conflict:    state == 'U',
+		}
+		if submodule.Initialized {
+			status, err := r.Run(ctx, "-C", filepath.Join(root, path), "status", "--porcelain")
+			if err != nil {
+				return nil, fmt.Errorf("failed to get status of submodule %s: %v", path, err)
+			}
+			submodule.Dirty = status != ""
+		}
+		submodules = append(submodules, submodule)
+	}
+	return submodules, nil
+}
+
+func (r *ExecRepository) SubmoduleCommit(ctx context.Context, ref, path string) (string, error) {
+	out, err := r.Run(ctx, "ls-tree", "--full-tree", ref, "--", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s at %s: %v", path, ref, err)
+	}
+	// "160000 commit <hash>\t<path>" for a submodule
+	fields := strings.Fields(out)
+	if len(fields) < 3 || fields[1] != "commit" {
+		return "", nil
+	}
+	return fields[2], nil
+}
+
+func (r *ExecRepository) OpenSubmodule(ctx context.Context, path string) (Repository, error) {
+	root, err := r.Run(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	return NewExecRepository(filepath.Join(root, path)), nil
+}
+
+func (r *ExecRepository) ReadNote(ctx context.Context, notesRef, commit string) (string, error) {
+	// notes list fails when there is no note, and also for a bad commit
+	if _, err := r.runUntranslated(ctx, "notes", "--ref", notesRef, "list", commit); err != nil {
+		if strings.Contains(err.Error(), "no note found") {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read note on %s: %v", commit, err)
+	}
+	note, err := r.output(ctx, nil, "notes", "--ref", notesRef, "show", commit)
+	if err != nil {
+		return "", fmt.Errorf("failed to read note on %s: %v", commit, err)
+	}
+	return note, nil
+}
+
+func (r *ExecRepository) WriteNote(ctx context.Context, notesRef, commit, content string) error {
+	if _, err := r.output(ctx, strings.NewReader(content), "notes", "--ref", notesRef, "add", "--force", "--file", "-", commit); err != nil {
+		return fmt.Errorf("failed to write note on %s: %v", commit, err)
+	}
+	return nil
+}
+
+func (r *ExecRepository) AddWorktree(ctx context.Context, dir, ref string) (Repository, error) {
+	if _, err := r.Run(ctx, "worktree", "add", "--detach", dir, ref); err != nil {
+		return nil, fmt.Errorf("failed to add worktree for %s: %v", ref, err)
+	}
+	return NewExecRepository(dir), nil
+}
+
+func (r *ExecRepository) RemoveWorktree(ctx context.Context, dir string) error {
+	if _, err := r.Run(ctx, "worktree", "remove", "--force", dir); err != nil {
+		return fmt.Errorf("failed to remove worktree %s: %v", dir, err)
+	}
+	return nil
+}
+
+func (r *ExecRepository) CatFile(ctx context.Context, ref string) (string, string, error) {
+	objectType, err := r.Run(ctx, "cat-file", "-t", ref)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %v", ref, err)
+	}
+	content, err := r.output(ctx, nil, "cat-file", objectType, ref)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %v", ref, err)
 	}
-	return strings.TrimSpace(out.String()), nil
+	return objectType, content, nil
 }
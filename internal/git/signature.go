@@ -0,0 +1,222 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+)
+
+// SigningKeyEnv holds an armored private key used when no key file is
+// given, SigningPassphraseEnv the passphrase if that key is encrypted. This
+// lets CI runners sign from secrets without a gpg-agent.
+const (
+	SigningKeyEnv        = "CICD_SIGNING_KEY"
+	SigningPassphraseEnv = "CICD_SIGNING_PASSPHRASE"
+)
+
+// loadSigningKey reads the armored private key in path, or in
+// $CICD_SIGNING_KEY when path is empty.
+func loadSigningKey(path string) (*openpgp.Entity, error) {
+	var armored io.Reader
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %v", err)
+		}
+		armored = bytes.NewReader(data)
+	} else {
+		key := os.Getenv(SigningKeyEnv)
+		if key == "" {
+			return nil, fmt.Errorf("no signing key, use --signing-key or set $%s", SigningKeyEnv)
+		}
+		armored = strings.NewReader(key)
+	}
+	return readSigningKey(armored, []byte(os.Getenv(SigningPassphraseEnv)))
+}
+
+// readSigningKey returns the first entity in the armored key ring that has
+// a private key, decrypted with passphrase if needed.
+func readSigningKey(armored io.Reader, passphrase []byte) (*openpgp.Entity, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(armored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %v", err)
+	}
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		if entity.PrivateKey.Encrypted {
+			if len(passphrase) == 0 {
+				return nil, fmt.Errorf("signing key is encrypted, set $%s", SigningPassphraseEnv)
+			}
+			if err := entity.DecryptPrivateKeys(passphrase); err != nil {
+				return nil, fmt.Errorf("failed to decrypt signing key: %v", err)
+			}
+		}
+		return entity, nil
+	}
+	return nil, fmt.Errorf("signing key does not contain a private key")
+}
+
+// detachedSigner returns a TagOptions.Sign function producing armored
+// detached signatures with entity, which git verify-tag understands.
+func detachedSigner(entity *openpgp.Entity) func(payload []byte) (string, error) {
+	return func(payload []byte) (string, error) {
+		var sb strings.Builder
+		if err := openpgp.ArmoredDetachSign(&sb, entity, bytes.NewReader(payload), nil); err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+}
+
+// Reasons a signature check fails.
+const (
+	reasonUnsigned         = "unsigned"
+	reasonUnknownKey       = "unknown key"
+	reasonBadSignature     = "bad signature"
+	reasonExpiredKey       = "expired key"
+	reasonExpiredSignature = "expired signature"
+	reasonRevokedKey       = "revoked key"
+)
+
+// signatureCheck is the outcome of verifying the signature of a tag or
+// commit. Reason is empty when the signature is good.
+type signatureCheck struct {
+	Object      string
+	Signer      string
+	Fingerprint string
+	Reason      string
+	Detail      string
+}
+
+func (c signatureCheck) String() string {
+	if c.Reason == "" {
+		return fmt.Sprintf("%s: good signature from %s, key %s", c.Object, c.Signer, c.Fingerprint)
+	}
+	msg := fmt.Sprintf("%s: %s", c.Object, c.Reason)
+	if c.Signer != "" {
+		msg += fmt.Sprintf(" from %s, key %s", c.Signer, c.Fingerprint)
+	}
+	if c.Detail != "" {
+		msg += " (" + c.Detail + ")"
+	}
+	return msg
+}
+
+// loadKeyring reads the public keys allowed to sign from path, which may
+// hold several armored blocks or a binary key ring.
+func loadKeyring(path string) (openpgp.EntityList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %v", err)
+	}
+	if !bytes.Contains(data, []byte("-----BEGIN PGP")) {
+		keyring, err := openpgp.ReadKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keyring %s: %v", path, err)
+		}
+		return keyring, nil
+	}
+	// armor.Decode buffers ahead, so split the blocks before decoding them
+	var keyring openpgp.EntityList
+	for _, block := range strings.SplitAfter(string(data), "-----END PGP PUBLIC KEY BLOCK-----") {
+		if !strings.Contains(block, "-----BEGIN PGP") {
+			continue
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(block))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keyring %s: %v", path, err)
+		}
+		keyring = append(keyring, entities...)
+	}
+	if len(keyring) == 0 {
+		return nil, fmt.Errorf("keyring %s contains no keys", path)
+	}
+	return keyring, nil
+}
+
+const pgpSignatureHeader = "-----BEGIN PGP SIGNATURE-----"
+
+// splitTagSignature separates the signature git appends to a tag object
+// from the signed payload.
+func splitTagSignature(object string) (payload, signature string) {
+	i := strings.Index(object, "\n"+pgpSignatureHeader)
+	if i < 0 {
+		return object, ""
+	}
+	return object[:i+1], object[i+1:]
+}
+
+// splitCommitSignature removes the gpgsig headers from a commit object and
+// returns what remains, which is what was signed, and the signature.
+func splitCommitSignature(object string) (payload, signature string) {
+	header, message, _ := strings.Cut(object, "\n\n")
+	var kept, sig []string
+	inSignature := ""
+	for _, line := range strings.Split(header, "\n") {
+		if inSignature != "" && strings.HasPrefix(line, " ") {
+			if inSignature == "gpgsig" {
+				sig = append(sig, line[1:])
+			}
+			continue
+		}
+		inSignature = ""
+		if name, value, _ := strings.Cut(line, " "); name == "gpgsig" || name == "gpgsig-sha256" {
+			inSignature = name
+			if name == "gpgsig" {
+				sig = append(sig, value)
+			}
+			continue
+		}
+		kept = append(kept, line)
+	}
+	payload = strings.Join(kept, "\n") + "\n\n" + message
+	if len(sig) == 0 {
+		return payload, ""
+	}
+	return payload, strings.Join(sig, "\n") + "\n"
+}
+
+// verifySignature checks the armored detached signature of payload
+// against keyring.
+func verifySignature(keyring openpgp.KeyRing, object, payload, signature string) signatureCheck {
+	check := signatureCheck{Object: object}
+	if !strings.HasPrefix(signature, pgpSignatureHeader) {
+		check.Reason = reasonUnsigned
+		return check
+	}
+	block, err := armor.Decode(strings.NewReader(signature))
+	if err != nil {
+		check.Reason, check.Detail = reasonBadSignature, err.Error()
+		return check
+	}
+	_, signer, err := openpgp.VerifyDetachedSignature(keyring, strings.NewReader(payload), block.Body, nil)
+	if signer != nil {
+		if identity := signer.PrimaryIdentity(); identity != nil {
+			check.Signer = identity.Name
+		}
+		check.Fingerprint = fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, pgperrors.ErrUnknownIssuer):
+		check.Reason = reasonUnknownKey
+	case errors.Is(err, pgperrors.ErrKeyExpired):
+		check.Reason = reasonExpiredKey
+	case errors.Is(err, pgperrors.ErrSignatureExpired):
+		check.Reason = reasonExpiredSignature
+	case errors.Is(err, pgperrors.ErrKeyRevoked):
+		check.Reason = reasonRevokedKey
+	default:
+		check.Reason, check.Detail = reasonBadSignature, err.Error()
+	}
+	return check
+}
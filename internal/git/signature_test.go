@@ -0,0 +1,176 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+func newArmoredTestKey(t *testing.T, passphrase []byte) (string, openpgp.EntityList) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Release Bot", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != nil {
+		if err := entity.EncryptPrivateKeys(passphrase, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.String(), openpgp.EntityList{entity}
+}
+
+func TestReadSigningKey(t *testing.T) {
+	armored, _ := newArmoredTestKey(t, []byte("secret"))
+
+	if _, err := readSigningKey(strings.NewReader(armored), nil); err == nil {
+		t.Errorf("expected an error for an encrypted key without passphrase")
+	}
+	if _, err := readSigningKey(strings.NewReader(armored), []byte("wrong")); err == nil {
+		t.Errorf("expected an error for a wrong passphrase")
+	}
+	if _, err := readSigningKey(strings.NewReader(armored), []byte("secret")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBumpGitTagSigned(t *testing.T) {
+	armored, keyring := newArmoredTestKey(t, nil)
+	t.Setenv(SigningKeyEnv, armored)
+
+	repo := NewFakeRepository()
+	repo.Commit("chore: initial commit")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.Commit("feat: add --sign")
+	ctx := WithRepository(context.Background(), repo)
+
+	err := executeBumpGitTag(ctx, &BumpGitTagOptions{Prefix: "v", Remote: "origin", Sign: true, Changelog: true}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	object, ok := repo.TagObjects["v1.1.0"]
+	if !ok {
+		t.Fatalf("expected an annotated tag v1.1.0, got %v", repo.TagNames())
+	}
+	payload, signature, found := strings.Cut(object, "-----BEGIN PGP SIGNATURE-----")
+	if !found {
+		t.Fatalf("expected a signature in tag object:\n%s", object)
+	}
+	if !strings.Contains(payload, "\nRelease v1.1.0\n") || !strings.Contains(payload, "- add --sign") {
+		t.Errorf("expected message and changelog in tag object:\n%s", payload)
+	}
+	signature = "-----BEGIN PGP SIGNATURE-----" + signature
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(payload), strings.NewReader(signature), nil)
+	if err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+}
+
+func signWith(t *testing.T, entity *openpgp.Entity, payload string, config *packet.Config) string {
+	t.Helper()
+	var sb strings.Builder
+	if err := openpgp.ArmoredDetachSign(&sb, entity, strings.NewReader(payload), config); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String() + "\n"
+}
+
+func TestVerifyTag(t *testing.T) {
+	_, keyring := newArmoredTestKey(t, nil)
+	_, otherKeyring := newArmoredTestKey(t, nil)
+	signer := keyring[0]
+	past := time.Now().Add(-48 * time.Hour)
+	expiring, err := openpgp.NewEntity("Old Bot", "", "old@example.com", &packet.Config{
+		Time:            func() time.Time { return past },
+		KeyLifetimeSecs: 3600,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewFakeRepository()
+	head := repo.Commit("feat: first")
+	if err := repo.Tag("v0.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	payload := tagObject(head, "v1.0.0", "Bot <release@example.com> 1700000000 +0000", "Release v1.0.0")
+	if err := repo.Tag("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	repo.TagObjects["v1.0.0"] = payload + signWith(t, signer, payload, nil)
+	if err := repo.Tag("v1.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	repo.TagObjects["v1.0.1"] = strings.Replace(repo.TagObjects["v1.0.0"], "Release v1.0.0", "Release v1.0.1", 1)
+	if err := repo.Tag("v1.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	repo.TagObjects["v1.0.2"] = payload + signWith(t, expiring, payload, &packet.Config{Time: func() time.Time { return past.Add(time.Minute) }})
+	ctx := context.Background()
+
+	tests := []struct {
+		tag     string
+		keyring openpgp.EntityList
+		reason  string
+	}{
+		{"v1.0.0", keyring, ""},
+		{"v0.1.0", keyring, reasonUnsigned},
+		{"v1.0.0", otherKeyring, reasonUnknownKey},
+		{"v1.0.1", keyring, reasonBadSignature},
+		{"v1.0.2", openpgp.EntityList{expiring}, reasonExpiredKey},
+	}
+	for _, tc := range tests {
+		check, err := verifyTag(ctx, repo, tc.keyring, tc.tag)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.tag, err)
+			continue
+		}
+		if check.Reason != tc.reason {
+			t.Errorf("%s: expected reason %q, got %q", tc.tag, tc.reason, check)
+		}
+		if tc.reason == "" && check.Fingerprint != fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint) {
+			t.Errorf("%s: unexpected fingerprint %s", tc.tag, check.Fingerprint)
+		}
+	}
+}
+
+func TestSplitCommitSignature(t *testing.T) {
+	_, keyring := newArmoredTestKey(t, nil)
+	payload := "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+		"author A <a@example.com> 1700000000 +0000\n" +
+		"committer A <a@example.com> 1700000000 +0000\n" +
+		"\n" +
+		"feat: signed\n\nWith a body.\n"
+	signature := signWith(t, keyring[0], payload, nil)
+	header, message, _ := strings.Cut(payload, "\n\n")
+	object := header + "\ngpgsig " + strings.ReplaceAll(strings.TrimSuffix(signature, "\n"), "\n", "\n ") + "\n\n" + message
+
+	gotPayload, gotSignature := splitCommitSignature(object)
+	if gotPayload != payload {
+		t.Errorf("expected payload %q, got %q", payload, gotPayload)
+	}
+	if check := verifySignature(keyring, "commit", gotPayload, gotSignature); check.Reason != "" {
+		t.Errorf("expected a good signature, got %s", check)
+	}
+	if _, sig := splitCommitSignature(payload); sig != "" {
+		t.Errorf("expected no signature, got %q", sig)
+	}
+}
@@ -0,0 +1,7 @@
+package main
+
+import "example.com/app/internal/config"
+
+func main() {
+	config.Load()
+}
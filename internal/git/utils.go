@@ -1,18 +1,85 @@
 package git
 
 import (
-	"fmt"
+	"context"
 	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/ci"
 )
 
+// GetCurrentBranch returns the branch checked out in the current directory.
 func GetCurrentBranch() (string, error) {
-	branch, err := Run("rev-parse", "--abbrev-ref", "HEAD")
-	if err != nil {
-		return "", fmt.Errorf("failed to get current branch: %v", err)
+	return NewExecRepository("").CurrentBranch(context.Background())
+}
+
+type getenvKey struct{}
+
+// WithGetenv returns a context whose commands read the CI environment
+// through env instead of the process environment.
+func WithGetenv(ctx context.Context, env ci.Getenv) context.Context {
+	return context.WithValue(ctx, getenvKey{}, env)
+}
+
+// detectBuild returns the CI build the command runs in, see WithGetenv.
+func detectBuild(ctx context.Context) ci.Build {
+	if env, ok := ctx.Value(getenvKey{}).(ci.Getenv); ok {
+		return ci.DetectEnv(env)
 	}
-	return branch, nil
+	return ci.Detect()
 }
 
 func splitLines(output string) []string {
 	return strings.Split(strings.TrimSpace(output), "\n")
 }
+
+// getDefaultBaseRef guesses the branch that work is merged into: the pull
+// or merge request base in CI, otherwise the remote's default branch.
+func getDefaultBaseRef(ctx context.Context, repo Repository) string {
+	if base := detectBuild(ctx).BaseBranch; base != "" {
+		return "origin/" + base
+	}
+	ref, err := repo.Run(ctx, "symbolic-ref", "--quiet", "refs/remotes/origin/HEAD")
+	if err == nil && ref != "" {
+		return strings.TrimPrefix(ref, "refs/remotes/")
+	}
+	return "origin/main"
+}
+
+// Defaults shared by several commands. Options left empty on the command
+// line are filled in by each command, the same values init.go shows in
+// the help.
+const (
+	defaultRemote              = "origin"
+	defaultEnvPrefix           = "BUILD_"
+	defaultReleaseBranchPrefix = "release/"
+	defaultNotesRef            = "refs/notes/cicd"
+)
+
+// splitList splits a comma separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// shellQuote quotes s for use as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// CommitFiles stages files and commits them with message.
+func CommitFiles(ctx context.Context, message string, files ...string) error {
+	repo := RepositoryFromContext(ctx)
+	if _, err := repo.Run(ctx, append([]string{"add", "--"}, files...)...); err != nil {
+		return err
+	}
+	if _, err := repo.Run(ctx, append([]string{"commit", "-m", message, "--"}, files...)...); err != nil {
+		return err
+	}
+	return nil
+}
@@ -10,21 +10,24 @@ import (
 	"os"
 	"strings"
 
+	"github.com/davidjspooner/cicd-utilities/pkg/ci"
 	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
 )
 
 type GithubPRUpdateOptions struct {
-	PRNumber string `flag:"<pr-number>,Pull request number"`
+	PRNumber string `flag:"--pr,Pull request number (default: the first argument or the pull request of the CI run)"`
 	DryRun   bool   `flag:"--dry-run,Do not update the PR title"`
 }
 
 func executeUpdateGithubPRMeta(ctx context.Context, option *GithubPRUpdateOptions, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: update-github-pr-meta <pr-number> [--dry-run]")
+	if option.PRNumber == "" && len(args) > 0 {
+		option.PRNumber = args[0]
+	}
+	if option.PRNumber == "" {
+		option.PRNumber = ci.Detect().PullRequest
 	}
-
 	if option.PRNumber == "" {
-		return fmt.Errorf("pull request number is required")
+		return fmt.Errorf("pull request number is required, use --pr outside of a pull request build")
 	}
 
 	token := os.Getenv("GITHUB_TOKEN")
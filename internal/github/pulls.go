@@ -0,0 +1,82 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// PullRequest describes a pull request to open.
+type PullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	// Head is the branch with the changes, Base the branch to merge into.
+	Head string `json:"head"`
+	Base string `json:"base"`
+}
+
+// PullRequests opens pull requests for the git commands, see
+// git.PullRequestOpener.
+type PullRequests struct{}
+
+func (PullRequests) CheckEnvironment() error {
+	return CheckEnvironment()
+}
+
+func (PullRequests) OpenPullRequest(ctx context.Context, title, body, head, base string) (string, error) {
+	return CreatePullRequest(ctx, PullRequest{Title: title, Body: body, Head: head, Base: base})
+}
+
+// CheckEnvironment reports whether the GitHub API can be used, so that
+// callers can fail before doing any work.
+func CheckEnvironment() error {
+	if os.Getenv("GITHUB_TOKEN") == "" || os.Getenv("GITHUB_REPOSITORY") == "" {
+		return fmt.Errorf("GITHUB_TOKEN and GITHUB_REPOSITORY environment variables are required")
+	}
+	return nil
+}
+
+// CreatePullRequest opens pr in $GITHUB_REPOSITORY and returns its URL.
+func CreatePullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	if err := CheckEnvironment(); err != nil {
+		return "", err
+	}
+	token := os.Getenv("GITHUB_TOKEN")
+	repo := os.Getenv("GITHUB_REPOSITORY") // e.g., "owner/repo"
+
+	url := fmt.Sprintf("https://api.github.com/repos/%s/pulls", repo)
+	data, err := json.Marshal(pr)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pull request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to create pull request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to create pull request, status code: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse pull request response: %v", err)
+	}
+	return result.HTMLURL, nil
+}
@@ -0,0 +1,29 @@
+package versionfiles
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/davidjspooner/cicd-utilities/pkg/versionfile"
+)
+
+type SetOptions struct {
+	Commit  bool   `flag:"--commit,Create a release commit with the updated files"`
+	Message string `flag:"--message,Message for the release commit (default: chore(release): v plus the version)"`
+}
+
+func executeSet(ctx context.Context, option *SetOptions, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: version-files set <version> [files...]")
+	}
+	version := versionfile.Normalize(args[0])
+	files, err := resolveFiles(args[1:])
+	if err != nil {
+		return err
+	}
+	message := option.Message
+	if message == "" {
+		message = fmt.Sprintf("chore(release): v%s", version)
+	}
+	return writeVersion(ctx, version, files, option.Commit, message)
+}
@@ -0,0 +1,77 @@
+package versionfiles
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/davidjspooner/cicd-utilities/internal/git"
+)
+
+func TestSet(t *testing.T) {
+	dir := t.TempDir()
+	chart := filepath.Join(dir, "Chart.yaml")
+	goFile := filepath.Join(dir, "version.go")
+	if err := os.WriteFile(chart, []byte("name: app\nversion: 1.0.0\nappVersion: \"1.0.0\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(goFile, []byte("package main\n\nconst Version = \"1.0.0\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo := &stubRepository{}
+	ctx := git.WithRepository(context.Background(), repo)
+
+	if err := executeSet(ctx, &SetOptions{Commit: true}, []string{"v1.2.0", chart, goFile}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testcases := []struct {
+		path     string
+		expected string
+	}{
+		{chart, "name: app\nversion: 1.2.0\nappVersion: \"1.2.0\"\n"},
+		{goFile, "package main\n\nconst Version = \"1.2.0\"\n"},
+	}
+	for _, tc := range testcases {
+		content, err := os.ReadFile(tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != tc.expected {
+			t.Errorf("%s: expected %q, got %q", filepath.Base(tc.path), tc.expected, content)
+		}
+	}
+	if !slices.Contains(repo.calls, "commit -m chore(release): v1.2.0 -- "+chart+" "+goFile) {
+		t.Errorf("expected a release commit, got %v", repo.calls)
+	}
+
+	// nothing changes, so no second commit
+	repo.calls = nil
+	if err := executeSet(ctx, &SetOptions{Commit: true}, []string{"1.2.0", chart, goFile}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.calls) != 0 {
+		t.Errorf("expected no commit when the files are up to date, got %v", repo.calls)
+	}
+}
+
+func TestSetNoMatch(t *testing.T) {
+	goFile := filepath.Join(t.TempDir(), "version.go")
+	original := "package main\n\nvar Version = \"1.0.0\"\n"
+	if err := os.WriteFile(goFile, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := git.WithRepository(context.Background(), &stubRepository{})
+
+	if err := executeSet(ctx, &SetOptions{}, []string{"1.2.0", goFile}); err == nil {
+		t.Errorf("expected an error for a Go file without a const Version")
+	}
+	content, err := os.ReadFile(goFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != original {
+		t.Errorf("expected the file to be left alone, got %q", content)
+	}
+}
@@ -0,0 +1,68 @@
+package versionfiles
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/internal/git"
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+	"github.com/davidjspooner/cicd-utilities/pkg/versionfile"
+)
+
+type SyncOptions struct {
+	Check   bool   `flag:"--check,Fail if a file disagrees with the latest tag instead of updating it"`
+	Commit  bool   `flag:"--commit,Create a release commit with the updated files"`
+	Message string `flag:"--message,Message for the release commit (default: chore(release): plus the tag)"`
+	Scheme  string `flag:"--scheme,Version scheme (semver|calver)"`
+	Format  string `flag:"--calver-format,CalVer format built from YYYY YY 0M MM DD and MICRO"`
+}
+
+func executeSync(ctx context.Context, option *SyncOptions, args []string) error {
+	scheme, err := semantic.LookupScheme(option.Scheme, option.Format)
+	if err != nil {
+		return err
+	}
+	files, err := resolveFiles(args)
+	if err != nil {
+		return err
+	}
+
+	latestTag, err := git.GetLatestTag(ctx, scheme, "HEAD", "")
+	if err != nil {
+		return fmt.Errorf("failed to get the latest tag: %v", err)
+	}
+	_, _, latestVersion, err := scheme.ExtractVersionFromTag(latestTag)
+	if err != nil {
+		return fmt.Errorf("failed to extract version from tag: %v", err)
+	}
+	version := latestVersion.String()
+	slog.Debug("Latest", "tag", latestTag, "version", version)
+
+	if !option.Check {
+		message := option.Message
+		if message == "" {
+			message = fmt.Sprintf("chore(release): %s", latestTag)
+		}
+		return writeVersion(ctx, version, files, option.Commit, message)
+	}
+
+	var mismatches []string
+	for _, file := range files {
+		versions, err := versionfile.ReadVersions(file)
+		if err != nil {
+			return err
+		}
+		for _, found := range versions {
+			if versionfile.Normalize(found) != version {
+				mismatches = append(mismatches, fmt.Sprintf("%s has %s", file, found))
+			}
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("version files disagree with tag %s: %s", latestTag, strings.Join(mismatches, ", "))
+	}
+	slog.Info("Version files agree with the latest tag", "tag", latestTag, "files", files)
+	return nil
+}
@@ -0,0 +1,29 @@
+package versionfiles
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidjspooner/cicd-utilities/internal/git"
+)
+
+func TestSyncCheck(t *testing.T) {
+	dir := t.TempDir()
+	packageJSON := filepath.Join(dir, "package.json")
+	if err := os.WriteFile(packageJSON, []byte("{\n  \"version\": \"1.1.0\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo := &stubRepository{tags: []git.Tag{{Name: "v1.1.0"}}}
+	ctx := git.WithRepository(context.Background(), repo)
+
+	option := &SyncOptions{Check: true, Scheme: "semver"}
+	if err := executeSync(ctx, option, []string{packageJSON}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	repo.tags = append(repo.tags, git.Tag{Name: "v1.2.0"})
+	if err := executeSync(ctx, option, []string{packageJSON}); err == nil {
+		t.Errorf("expected an error when package.json is behind the latest tag")
+	}
+}
@@ -0,0 +1,32 @@
+package versionfiles
+
+import (
+	"github.com/davidjspooner/cicd-utilities/pkg/command"
+	"github.com/davidjspooner/cicd-utilities/pkg/semantic"
+)
+
+func Commands() []command.Command {
+	versionFilesCommand := command.NewCommand(
+		"version-files",
+		"Version file commands (package.json, Chart.yaml, pyproject.toml, Cargo.toml, Go const Version)",
+		nil,
+		&command.NoopOptions{},
+	)
+	setCmd := command.NewCommand(
+		"set",
+		"Write a version into version files",
+		executeSet,
+		&SetOptions{},
+	)
+	syncCmd := command.NewCommand(
+		"sync",
+		"Write the version of the latest tag into version files, or check they agree with it",
+		executeSync,
+		&SyncOptions{
+			Scheme: "semver",
+			Format: semantic.DefaultCalVerFormat,
+		},
+	)
+	versionFilesCommand.SubCommands().MustAdd(setCmd, syncCmd)
+	return []command.Command{versionFilesCommand}
+}
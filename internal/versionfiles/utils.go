@@ -0,0 +1,48 @@
+package versionfiles
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/davidjspooner/cicd-utilities/internal/git"
+	"github.com/davidjspooner/cicd-utilities/pkg/versionfile"
+)
+
+func resolveFiles(files []string) ([]string, error) {
+	if len(files) > 0 {
+		return files, nil
+	}
+	files = versionfile.DefaultFiles(".")
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files specified and no well known version files found")
+	}
+	return files, nil
+}
+
+func writeVersion(ctx context.Context, version string, files []string, commit bool, message string) error {
+	var changedFiles []string
+	for _, file := range files {
+		changed, err := versionfile.WriteVersion(file, version)
+		if err != nil {
+			return err
+		}
+		slog.Debug("Version file", "file", file, "version", version, "changed", changed)
+		if changed {
+			changedFiles = append(changedFiles, file)
+		}
+	}
+	if len(changedFiles) == 0 {
+		slog.Info("Version files already up to date", "version", version)
+		return nil
+	}
+	slog.Info("Version files updated", "version", version, "files", changedFiles)
+	if !commit {
+		return nil
+	}
+	if err := git.CommitFiles(ctx, message, changedFiles...); err != nil {
+		return fmt.Errorf("failed to create release commit: %v", err)
+	}
+	slog.Info("Release commit created", "message", message)
+	return nil
+}
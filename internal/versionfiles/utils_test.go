@@ -0,0 +1,25 @@
+package versionfiles
+
+import (
+	"context"
+	"strings"
+
+	"github.com/davidjspooner/cicd-utilities/internal/git"
+)
+
+// stubRepository implements the parts of git.Repository the version file
+// commands use: Run, which records its arguments, and Tags.
+type stubRepository struct {
+	git.Repository
+	calls []string
+	tags  []git.Tag
+}
+
+func (r *stubRepository) Run(_ context.Context, args ...string) (string, error) {
+	r.calls = append(r.calls, strings.Join(args, " "))
+	return "", nil
+}
+
+func (r *stubRepository) Tags(_ context.Context, merged string) ([]git.Tag, error) {
+	return r.tags, nil
+}
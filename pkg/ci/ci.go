@@ -0,0 +1,211 @@
+// Package ci detects the CI system a process runs in and normalises what
+// each system exposes through its environment variables.
+package ci
+
+import (
+	"os"
+	"strings"
+)
+
+// Build describes the CI run. All fields are empty outside of CI.
+type Build struct {
+	// Provider is one of github, gitlab, jenkins, buildkite, circleci,
+	// azure or drone.
+	Provider string
+	RunID    string
+	RunURL   string
+	Job      string
+	// PullRequest is the number of the pull or merge request being built.
+	PullRequest string
+	// BaseBranch is the branch the pull or merge request merges into.
+	BaseBranch string
+	Branch     string
+	Tag        string
+	// Actor is the user who triggered the run, when the system exposes it.
+	Actor string
+}
+
+// IsCI reports whether a CI system was detected.
+func (b Build) IsCI() bool {
+	return b.Provider != ""
+}
+
+// IsTag reports whether the run was triggered by a tag.
+func (b Build) IsTag() bool {
+	return b.Tag != ""
+}
+
+// Getenv looks up an environment variable, os.Getenv outside of tests.
+type Getenv func(key string) string
+
+type provider struct {
+	name   string
+	detect func(env Getenv) bool
+	build  func(env Getenv) Build
+}
+
+// providers are checked in order, the generic Jenkins check comes last.
+var providers = []provider{
+	{"github", isSet("GITHUB_ACTIONS"), github},
+	{"gitlab", isSet("GITLAB_CI"), gitlab},
+	{"buildkite", isSet("BUILDKITE"), buildkite},
+	{"circleci", isSet("CIRCLECI"), circleci},
+	{"azure", isSet("TF_BUILD"), azure},
+	{"drone", isSet("DRONE"), drone},
+	{"jenkins", isSet("JENKINS_URL"), jenkins},
+}
+
+// Detect returns the build of the CI system found in the environment.
+func Detect() Build {
+	return DetectEnv(os.Getenv)
+}
+
+// DetectEnv is Detect with the environment read through env.
+func DetectEnv(env Getenv) Build {
+	for _, p := range providers {
+		if p.detect(env) {
+			build := p.build(env)
+			build.Provider = p.name
+			return build
+		}
+	}
+	return Build{}
+}
+
+func isSet(key string) func(env Getenv) bool {
+	return func(env Getenv) bool {
+		value := strings.ToLower(env(key))
+		return value != "" && value != "false"
+	}
+}
+
+// first returns the first non empty value of keys.
+func first(env Getenv, keys ...string) string {
+	for _, key := range keys {
+		if value := env(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func github(env Getenv) Build {
+	b := Build{
+		RunID: env("GITHUB_RUN_ID"),
+		Job:   env("GITHUB_JOB"),
+		Actor: first(env, "GITHUB_TRIGGERING_ACTOR", "GITHUB_ACTOR"),
+	}
+	if server, repo := env("GITHUB_SERVER_URL"), env("GITHUB_REPOSITORY"); server != "" && repo != "" && b.RunID != "" {
+		b.RunURL = server + "/" + repo + "/actions/runs/" + b.RunID
+	}
+	ref := env("GITHUB_REF")
+	if number, ok := strings.CutPrefix(ref, "refs/pull/"); ok {
+		b.PullRequest, _, _ = strings.Cut(number, "/")
+		b.BaseBranch = env("GITHUB_BASE_REF")
+	}
+	switch {
+	case strings.HasPrefix(ref, "refs/tags/"):
+		b.Tag = strings.TrimPrefix(ref, "refs/tags/")
+	case env("GITHUB_HEAD_REF") != "":
+		b.Branch = env("GITHUB_HEAD_REF")
+	default:
+		b.Branch = strings.TrimPrefix(ref, "refs/heads/")
+	}
+	return b
+}
+
+func gitlab(env Getenv) Build {
+	return Build{
+		RunID:       env("CI_PIPELINE_ID"),
+		RunURL:      env("CI_PIPELINE_URL"),
+		Job:         env("CI_JOB_NAME"),
+		PullRequest: env("CI_MERGE_REQUEST_IID"),
+		BaseBranch:  env("CI_MERGE_REQUEST_TARGET_BRANCH_NAME"),
+		Branch:      first(env, "CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_BRANCH"),
+		Tag:         env("CI_COMMIT_TAG"),
+		Actor:       env("GITLAB_USER_LOGIN"),
+	}
+}
+
+func buildkite(env Getenv) Build {
+	b := Build{
+		RunID:  env("BUILDKITE_BUILD_NUMBER"),
+		RunURL: env("BUILDKITE_BUILD_URL"),
+		Job:    first(env, "BUILDKITE_LABEL", "BUILDKITE_STEP_KEY"),
+		Branch: env("BUILDKITE_BRANCH"),
+		Tag:    env("BUILDKITE_TAG"),
+		Actor:  env("BUILDKITE_BUILD_CREATOR"),
+	}
+	// BUILDKITE_PULL_REQUEST is "false" for branch builds
+	if pr := env("BUILDKITE_PULL_REQUEST"); pr != "false" {
+		b.PullRequest = pr
+		b.BaseBranch = env("BUILDKITE_PULL_REQUEST_BASE_BRANCH")
+	}
+	return b
+}
+
+func circleci(env Getenv) Build {
+	b := Build{
+		RunID:       env("CIRCLE_BUILD_NUM"),
+		RunURL:      env("CIRCLE_BUILD_URL"),
+		Job:         env("CIRCLE_JOB"),
+		PullRequest: env("CIRCLE_PR_NUMBER"),
+		Branch:      env("CIRCLE_BRANCH"),
+		Tag:         env("CIRCLE_TAG"),
+		Actor:       env("CIRCLE_USERNAME"),
+	}
+	if url := env("CIRCLE_PULL_REQUEST"); b.PullRequest == "" && url != "" {
+		b.PullRequest = url[strings.LastIndex(url, "/")+1:]
+	}
+	return b
+}
+
+func azure(env Getenv) Build {
+	b := Build{
+		RunID:       env("BUILD_BUILDID"),
+		Job:         first(env, "SYSTEM_JOBDISPLAYNAME", "AGENT_JOBNAME"),
+		PullRequest: first(env, "SYSTEM_PULLREQUEST_PULLREQUESTNUMBER", "SYSTEM_PULLREQUEST_PULLREQUESTID"),
+		BaseBranch:  strings.TrimPrefix(env("SYSTEM_PULLREQUEST_TARGETBRANCH"), "refs/heads/"),
+		Actor:       env("BUILD_REQUESTEDFOR"),
+	}
+	if collection, project := env("SYSTEM_COLLECTIONURI"), env("SYSTEM_TEAMPROJECT"); collection != "" && project != "" && b.RunID != "" {
+		b.RunURL = strings.TrimSuffix(collection, "/") + "/" + project + "/_build/results?buildId=" + b.RunID
+	}
+	ref := first(env, "SYSTEM_PULLREQUEST_SOURCEBRANCH", "BUILD_SOURCEBRANCH")
+	if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+		b.Tag = tag
+	} else {
+		b.Branch = strings.TrimPrefix(ref, "refs/heads/")
+	}
+	return b
+}
+
+func drone(env Getenv) Build {
+	b := Build{
+		RunID:       env("DRONE_BUILD_NUMBER"),
+		RunURL:      env("DRONE_BUILD_LINK"),
+		Job:         first(env, "DRONE_STAGE_NAME", "DRONE_STEP_NAME"),
+		PullRequest: env("DRONE_PULL_REQUEST"),
+		Branch:      first(env, "DRONE_SOURCE_BRANCH", "DRONE_BRANCH"),
+		Tag:         env("DRONE_TAG"),
+		Actor:       first(env, "DRONE_BUILD_TRIGGER", "DRONE_COMMIT_AUTHOR"),
+	}
+	// DRONE_TARGET_BRANCH is the pushed branch itself outside of pull requests
+	if b.PullRequest != "" {
+		b.BaseBranch = env("DRONE_TARGET_BRANCH")
+	}
+	return b
+}
+
+func jenkins(env Getenv) Build {
+	return Build{
+		RunID:       env("BUILD_NUMBER"),
+		RunURL:      env("BUILD_URL"),
+		Job:         env("JOB_NAME"),
+		PullRequest: env("CHANGE_ID"),
+		BaseBranch:  env("CHANGE_TARGET"),
+		Branch:      strings.TrimPrefix(first(env, "CHANGE_BRANCH", "BRANCH_NAME", "GIT_BRANCH"), "origin/"),
+		Tag:         env("TAG_NAME"),
+		Actor:       first(env, "BUILD_USER_ID", "CHANGE_AUTHOR"),
+	}
+}
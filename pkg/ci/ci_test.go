@@ -0,0 +1,78 @@
+package ci
+
+import "testing"
+
+func TestDetectEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected Build
+	}{
+		{"local", map[string]string{"USER": "dev"}, Build{}},
+		{"github pull request", map[string]string{
+			"GITHUB_ACTIONS":    "true",
+			"GITHUB_RUN_ID":     "42",
+			"GITHUB_JOB":        "build",
+			"GITHUB_SERVER_URL": "https://github.com",
+			"GITHUB_REPOSITORY": "acme/app",
+			"GITHUB_REF":        "refs/pull/17/merge",
+			"GITHUB_HEAD_REF":   "feature/x",
+			"GITHUB_BASE_REF":   "main",
+			"GITHUB_ACTOR":      "octocat",
+		}, Build{Provider: "github", RunID: "42", RunURL: "https://github.com/acme/app/actions/runs/42", Job: "build", PullRequest: "17", BaseBranch: "main", Branch: "feature/x", Actor: "octocat"}},
+		{"github tag", map[string]string{
+			"GITHUB_ACTIONS": "true",
+			"GITHUB_REF":     "refs/tags/v1.0.0",
+		}, Build{Provider: "github", Tag: "v1.0.0"}},
+		{"gitlab merge request", map[string]string{
+			"GITLAB_CI":                           "true",
+			"CI_PIPELINE_ID":                      "1001",
+			"CI_PIPELINE_URL":                     "https://gitlab.com/acme/app/-/pipelines/1001",
+			"CI_JOB_NAME":                         "test",
+			"CI_MERGE_REQUEST_IID":                "5",
+			"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "fix/y",
+			"CI_MERGE_REQUEST_TARGET_BRANCH_NAME": "develop",
+		}, Build{Provider: "gitlab", RunID: "1001", RunURL: "https://gitlab.com/acme/app/-/pipelines/1001", Job: "test", PullRequest: "5", BaseBranch: "develop", Branch: "fix/y"}},
+		{"jenkins multibranch", map[string]string{
+			"JENKINS_URL":   "https://ci.example.com/",
+			"BUILD_NUMBER":  "7",
+			"BUILD_URL":     "https://ci.example.com/job/app/7/",
+			"JOB_NAME":      "app/PR-3",
+			"CHANGE_ID":     "3",
+			"CHANGE_BRANCH": "feature/z",
+			"CHANGE_TARGET": "main",
+		}, Build{Provider: "jenkins", RunID: "7", RunURL: "https://ci.example.com/job/app/7/", Job: "app/PR-3", PullRequest: "3", BaseBranch: "main", Branch: "feature/z"}},
+		{"buildkite branch", map[string]string{
+			"BUILDKITE":              "true",
+			"BUILDKITE_BUILD_NUMBER": "99",
+			"BUILDKITE_BRANCH":       "main",
+			"BUILDKITE_PULL_REQUEST": "false",
+		}, Build{Provider: "buildkite", RunID: "99", Branch: "main"}},
+		{"circleci fork pull request", map[string]string{
+			"CIRCLECI":            "true",
+			"CIRCLE_BUILD_NUM":    "12",
+			"CIRCLE_JOB":          "lint",
+			"CIRCLE_BRANCH":       "pull/8",
+			"CIRCLE_PULL_REQUEST": "https://github.com/acme/app/pull/8",
+		}, Build{Provider: "circleci", RunID: "12", Job: "lint", PullRequest: "8", Branch: "pull/8"}},
+		{"azure tag", map[string]string{
+			"TF_BUILD":             "True",
+			"BUILD_BUILDID":        "314",
+			"SYSTEM_COLLECTIONURI": "https://dev.azure.com/acme/",
+			"SYSTEM_TEAMPROJECT":   "app",
+			"BUILD_SOURCEBRANCH":   "refs/tags/v2.0.0",
+		}, Build{Provider: "azure", RunID: "314", RunURL: "https://dev.azure.com/acme/app/_build/results?buildId=314", Tag: "v2.0.0"}},
+		{"drone", map[string]string{
+			"DRONE":              "true",
+			"DRONE_BUILD_NUMBER": "4",
+			"DRONE_BRANCH":       "main",
+			"DRONE_STAGE_NAME":   "default",
+		}, Build{Provider: "drone", RunID: "4", Job: "default", Branch: "main"}},
+	}
+	for _, tc := range tests {
+		got := DetectEnv(func(key string) string { return tc.env[key] })
+		if got != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, got)
+		}
+	}
+}
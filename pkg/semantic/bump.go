@@ -36,3 +36,24 @@ func (bumps BumpArray) GetVersionBump(commits []string) (string, error) {
 
 	return "patch", nil
 }
+
+// GetMessageBump is GetVersionBump for whole commit messages. Messages are
+// parsed as conventional commits, so scopes, "!" and BREAKING CHANGE footers
+// count while words in the body do not. Messages that do not parse fall back
+// to the hints on their first line.
+func (bumps BumpArray) GetMessageBump(messages []string) (string, error) {
+	headers := make([]string, 0, len(messages))
+	for _, message := range messages {
+		c, err := ParseCommit(message)
+		switch {
+		case err != nil:
+			headers = append(headers, c.Header)
+		case c.Breaking:
+			headers = append(headers, "BREAKING CHANGE")
+		default:
+			// drop the scope so "feat(api): x" matches the "feat:" hint
+			headers = append(headers, c.Type+": "+c.Description)
+		}
+	}
+	return bumps.GetVersionBump(headers)
+}
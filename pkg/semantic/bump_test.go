@@ -0,0 +1,26 @@
+package semantic
+
+import "testing"
+
+func TestGetMessageBump(t *testing.T) {
+	tests := []struct {
+		messages []string
+		expected string
+	}{
+		{[]string{"fix: typo", "docs: mention fix: in the body is ignored\n\nfeat: not a header"}, "patch"},
+		{[]string{"fix: typo", "feat(api): add --json"}, "minor"},
+		{[]string{"feat(api)!: drop v1"}, "major"},
+		{[]string{"fix: rename flag\n\nBREAKING CHANGE: --xml is now --format xml"}, "major"},
+		{[]string{"Update readme", "Merge feat: old style"}, "minor"},
+	}
+	for _, tc := range tests {
+		bump, err := Bumps.GetMessageBump(tc.messages)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.messages, err)
+			continue
+		}
+		if bump != tc.expected {
+			t.Errorf("%v: expected %s, got %s", tc.messages, tc.expected, bump)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package semantic
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCalVerFormat is used when no format is given for the calver scheme.
+const DefaultCalVerFormat = "YYYY.0M.MICRO"
+
+type calVerToken struct {
+	name    string
+	pattern string
+	isDate  bool
+	value   func(now time.Time) int
+	format  func(value int) string
+}
+
+// calVerTokens are matched longest first, so YYYY wins over YY.
+var calVerTokens = []calVerToken{
+	{"YYYY", `\d{4}`, true, func(t time.Time) int { return t.Year() }, strconv.Itoa},
+	{"MICRO", `\d+`, false, nil, strconv.Itoa},
+	{"YY", `\d{1,3}`, true, func(t time.Time) int { return t.Year() - 2000 }, strconv.Itoa},
+	{"0M", `\d{2}`, true, func(t time.Time) int { return int(t.Month()) }, func(v int) string { return fmt.Sprintf("%02d", v) }},
+	{"MM", `\d{1,2}`, true, func(t time.Time) int { return int(t.Month()) }, strconv.Itoa},
+	{"DD", `\d{1,2}`, true, func(t time.Time) int { return t.Day() }, strconv.Itoa},
+}
+
+type calVerPart struct {
+	literal string
+	token   *calVerToken
+}
+
+// CalVerScheme is a calendar versioning scheme such as YYYY.0M.MICRO.
+type CalVerScheme struct {
+	format  string
+	parts   []calVerPart
+	pattern *regexp.Regexp
+}
+
+var _ Scheme = &CalVerScheme{}
+
+// NewCalVerScheme parses a format made of the tokens YYYY, YY, 0M, MM, DD and
+// MICRO separated by literal characters, e.g. "YYYY.0M.MICRO" or "YY.MM.DD-MICRO".
+func NewCalVerScheme(format string) (*CalVerScheme, error) {
+	if format == "" {
+		format = DefaultCalVerFormat
+	}
+	s := &CalVerScheme{format: format}
+	pattern := strings.Builder{}
+	// the prefix and suffix must not end or start in a digit, or a prefix
+	// of "20" would turn 2026.10.3 into YY 26
+	pattern.WriteString(`^((?:.*?[^0-9])?)`)
+	seen := map[string]bool{}
+	hasDate := false
+	for rest := format; rest != ""; {
+		var matched *calVerToken
+		for i := range calVerTokens {
+			if strings.HasPrefix(rest, calVerTokens[i].name) {
+				matched = &calVerTokens[i]
+				break
+			}
+		}
+		if matched == nil {
+			c := rest[:1]
+			if c[0] >= 'A' && c[0] <= 'Z' || c[0] >= '0' && c[0] <= '9' {
+				return nil, fmt.Errorf("invalid calver format %q: unknown token at %q", format, rest)
+			}
+			s.parts = append(s.parts, calVerPart{literal: c})
+			pattern.WriteString(regexp.QuoteMeta(c))
+			rest = rest[1:]
+			continue
+		}
+		if seen[matched.name] {
+			return nil, fmt.Errorf("invalid calver format %q: %s used more than once", format, matched.name)
+		}
+		seen[matched.name] = true
+		hasDate = hasDate || matched.isDate
+		s.parts = append(s.parts, calVerPart{token: matched})
+		pattern.WriteString("(" + matched.pattern + ")")
+		rest = rest[len(matched.name):]
+	}
+	if !hasDate {
+		return nil, fmt.Errorf("invalid calver format %q: no date token", format)
+	}
+	pattern.WriteString(`((?:[^0-9].*)?)$`)
+	s.pattern = regexp.MustCompile(pattern.String())
+	return s, nil
+}
+
+func (s *CalVerScheme) Name() string {
+	return "calver"
+}
+
+// Format returns the format the scheme was created with.
+func (s *CalVerScheme) Format() string {
+	return s.format
+}
+
+func (s *CalVerScheme) ExtractVersionFromTag(tag string) (string, string, Versioned, error) {
+	matches := s.pattern.FindStringSubmatch(tag)
+	if matches == nil {
+		return "", "", nil, fmt.Errorf("tag %s does not match calver format %s", tag, s.format)
+	}
+	v := CalVersion{scheme: s}
+	i := 1
+	for _, part := range s.parts {
+		if part.token == nil {
+			continue
+		}
+		i++
+		n, err := strconv.Atoi(matches[i])
+		if err != nil {
+			return "", "", nil, fmt.Errorf("error converting %s: %v", part.token.name, err)
+		}
+		switch part.token.name {
+		case "0M", "MM":
+			if n < 1 || n > 12 {
+				return "", "", nil, fmt.Errorf("invalid month %d in tag %s", n, tag)
+			}
+		case "DD":
+			if n < 1 || n > 31 {
+				return "", "", nil, fmt.Errorf("invalid day %d in tag %s", n, tag)
+			}
+		}
+		v.Values = append(v.Values, n)
+	}
+	return matches[1], matches[len(matches)-1], v, nil
+}
+
+// Increment ignores the bump: the date tokens come from now and MICRO counts
+// the releases made on the same date.
+func (s *CalVerScheme) Increment(current Versioned, _ string, now time.Time) (Versioned, error) {
+	next := s.Initial(now)
+	if current == nil {
+		return next, nil
+	}
+	c, ok := current.(CalVersion)
+	if !ok || len(c.Values) != len(next.Values) {
+		return nil, fmt.Errorf("%s is not a %s calendar version", current, s.format)
+	}
+	sameDate := true
+	microIndex := -1
+	for i, token := range s.tokens() {
+		if token.isDate {
+			sameDate = sameDate && c.Values[i] == next.Values[i]
+		} else {
+			microIndex = i
+		}
+	}
+	if sameDate {
+		if microIndex < 0 {
+			return nil, fmt.Errorf("version %s was already released today and format %s has no MICRO", c, s.format)
+		}
+		next.Values[microIndex] = c.Values[microIndex] + 1
+	}
+	if next.CompareTo(c) <= 0 {
+		return nil, fmt.Errorf("next version %s is not greater than %s, check the clock", next, c)
+	}
+	return next, nil
+}
+
+// Initial returns the first version for the date of now.
+func (s *CalVerScheme) Initial(now time.Time) CalVersion {
+	v := CalVersion{scheme: s}
+	for _, token := range s.tokens() {
+		if token.isDate {
+			v.Values = append(v.Values, token.value(now))
+		} else {
+			v.Values = append(v.Values, 0)
+		}
+	}
+	return v
+}
+
+func (s *CalVerScheme) tokens() []*calVerToken {
+	var tokens []*calVerToken
+	for _, part := range s.parts {
+		if part.token != nil {
+			tokens = append(tokens, part.token)
+		}
+	}
+	return tokens
+}
+
+// CalVersion is a calendar version. Values holds one number per token of the
+// scheme format, in format order.
+type CalVersion struct {
+	scheme *CalVerScheme
+	Values []int
+}
+
+func (v CalVersion) String() string {
+	if v.scheme == nil {
+		return "<invalid calver>"
+	}
+	sb := strings.Builder{}
+	i := 0
+	for _, part := range v.scheme.parts {
+		if part.token == nil {
+			sb.WriteString(part.literal)
+			continue
+		}
+		if i < len(v.Values) {
+			sb.WriteString(part.token.format(v.Values[i]))
+		}
+		i++
+	}
+	return sb.String()
+}
+
+func (v CalVersion) CompareTo(other Versioned) int {
+	o, ok := other.(CalVersion)
+	if !ok {
+		return strings.Compare(v.String(), other.String())
+	}
+	for i := 0; i < len(v.Values) && i < len(o.Values); i++ {
+		if v.Values[i] != o.Values[i] {
+			return v.Values[i] - o.Values[i]
+		}
+	}
+	return len(v.Values) - len(o.Values)
+}
@@ -0,0 +1,90 @@
+package semantic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalVerExtractVersionFromTag(t *testing.T) {
+	scheme, err := NewCalVerScheme("YY.MM.MICRO")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prefix, suffix, v, err := scheme.ExtractVersionFromTag("v26.10.0-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prefix != "v" || suffix != "-1" || v.String() != "26.10.0" {
+		t.Errorf("unexpected parse: prefix=%q suffix=%q version=%q", prefix, suffix, v)
+	}
+	if _, _, _, err := scheme.ExtractVersionFromTag("v26.13.0"); err == nil {
+		t.Error("expected error for month 13, got nil")
+	}
+	// digits are never split off into the prefix or the suffix
+	for _, tag := range []string{"2026.10.3", "release-2026.10.3"} {
+		if _, _, v, err := scheme.ExtractVersionFromTag(tag); err == nil {
+			t.Errorf("expected %s not to match YY.MM.MICRO, got %s", tag, v)
+		}
+	}
+	daily, err := NewCalVerScheme("YY.MM.DD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, v, err := daily.ExtractVersionFromTag("26.10.123"); err == nil {
+		t.Errorf("expected 26.10.123 not to match YY.MM.DD, got %s", v)
+	}
+}
+
+func TestCalVerIncrement(t *testing.T) {
+	scheme, err := NewCalVerScheme("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _, current, err := scheme.ExtractVersionFromTag("2026.10.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		now      time.Time
+		expected string
+	}{
+		{time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), "2026.10.4"},
+		{time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), "2026.11.0"},
+	}
+	for _, tc := range tests {
+		next, err := scheme.Increment(current, "patch", tc.now)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if next.String() != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, next)
+		}
+		if next.CompareTo(current) <= 0 {
+			t.Errorf("expected %s to be greater than %s", next, current)
+		}
+	}
+
+	if _, err := scheme.Increment(current, "patch", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("expected error when the clock is behind the latest version, got nil")
+	}
+}
+
+func TestCalVerFormatErrors(t *testing.T) {
+	for _, format := range []string{"MICRO", "YYYY.YYYY", "YYYY.QQ"} {
+		if _, err := NewCalVerScheme(format); err == nil {
+			t.Errorf("expected error for format %q, got nil", format)
+		}
+	}
+}
+
+func TestExtractVersionFromTagMultiDigit(t *testing.T) {
+	prefix, _, v, err := ExtractVersionFromTag("v10.20.30")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prefix != "v" || v.String() != "10.20.30" {
+		t.Errorf("unexpected parse: prefix=%q version=%s", prefix, v)
+	}
+}
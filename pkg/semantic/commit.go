@@ -0,0 +1,104 @@
+package semantic
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Footer is a git trailer style line at the end of a commit message, e.g.
+// "Refs: #123" or "BREAKING CHANGE: the config format changed".
+type Footer struct {
+	Token string
+	Value string
+}
+
+// Commit is a commit message parsed with the conventional commits grammar:
+//
+//	<type>[(<scope>)][!]: <description>
+//
+//	[body]
+//
+//	[footers]
+type Commit struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+	Header      string
+	Body        string
+	Footers     []Footer
+}
+
+var commitHeaderFmt = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()]*)\))?(!)?: (.*)$`)
+var commitFooterFmt = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[A-Za-z][A-Za-z0-9-]*)(?:: | #)(.*)$`)
+
+// ParseCommit parses a full commit message. An error is returned when the
+// header does not follow the grammar, but the Header, Body and Footers are
+// still filled in so callers can report on them.
+func ParseCommit(message string) (Commit, error) {
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+	message = strings.TrimSpace(message)
+	header, rest, _ := strings.Cut(message, "\n")
+
+	c := Commit{Header: strings.TrimSpace(header)}
+	c.Body, c.Footers = splitFooters(strings.TrimSpace(rest))
+	for _, footer := range c.Footers {
+		if footer.Token == "BREAKING CHANGE" || footer.Token == "BREAKING-CHANGE" {
+			c.Breaking = true
+		}
+	}
+
+	matches := commitHeaderFmt.FindStringSubmatch(c.Header)
+	if matches == nil {
+		return c, fmt.Errorf("header %q does not match <type>[(<scope>)][!]: <description>", c.Header)
+	}
+	c.Type = strings.ToLower(matches[1])
+	c.Scope = matches[2]
+	c.Breaking = c.Breaking || matches[3] == "!"
+	c.Description = strings.TrimSpace(matches[4])
+	if c.Description == "" {
+		return c, fmt.Errorf("header %q has an empty description", c.Header)
+	}
+	return c, nil
+}
+
+// Footer returns the value of the first footer with the given token, matched
+// case-insensitively.
+func (c Commit) Footer(token string) (string, bool) {
+	for _, footer := range c.Footers {
+		if strings.EqualFold(footer.Token, token) {
+			return footer.Value, true
+		}
+	}
+	return "", false
+}
+
+// splitFooters separates the trailing footer paragraph from the body.
+// Footer values may continue over several lines.
+func splitFooters(text string) (string, []Footer) {
+	if text == "" {
+		return "", nil
+	}
+	paragraphs := strings.Split(text, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	lines := strings.Split(last, "\n")
+	if !commitFooterFmt.MatchString(lines[0]) {
+		return text, nil
+	}
+	var footers []Footer
+	for _, line := range lines {
+		matches := commitFooterFmt.FindStringSubmatch(line)
+		if matches == nil {
+			footers[len(footers)-1].Value += "\n" + line
+			continue
+		}
+		value := matches[2]
+		if strings.HasPrefix(line[len(matches[1]):], " #") {
+			value = "#" + value
+		}
+		footers = append(footers, Footer{Token: matches[1], Value: value})
+	}
+	body := strings.TrimSpace(strings.Join(paragraphs[:len(paragraphs)-1], "\n\n"))
+	return body, footers
+}
@@ -0,0 +1,34 @@
+package semantic
+
+import "testing"
+
+func TestParseCommit(t *testing.T) {
+	message := "feat(api)!: drop the v1 endpoints\n\nThe v1 endpoints were deprecated last year.\n\nRefs #12\nBREAKING CHANGE: clients must use /v2\n  from now on"
+	c, err := ParseCommit(message)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Type != "feat" || c.Scope != "api" || !c.Breaking || c.Description != "drop the v1 endpoints" {
+		t.Errorf("unexpected header parse: %+v", c)
+	}
+	if c.Body != "The v1 endpoints were deprecated last year." {
+		t.Errorf("unexpected body: %q", c.Body)
+	}
+	if len(c.Footers) != 2 {
+		t.Fatalf("expected 2 footers, got %+v", c.Footers)
+	}
+	if value, _ := c.Footer("refs"); value != "#12" {
+		t.Errorf("unexpected Refs footer: %q", value)
+	}
+	if value, _ := c.Footer("BREAKING CHANGE"); value != "clients must use /v2\n  from now on" {
+		t.Errorf("unexpected BREAKING CHANGE footer: %q", value)
+	}
+}
+
+func TestParseCommitNegative(t *testing.T) {
+	for _, message := range []string{"Update readme", "feat:", "feat(scope: missing paren", ": no type"} {
+		if _, err := ParseCommit(message); err == nil {
+			t.Errorf("expected error for %q, got nil", message)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package semantic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a version range such as ">=1.2.0 <2.0.0", "~1.4", "^2.1.0",
+// "1.3.x" or several of those joined with "||".
+type Constraint struct {
+	text         string
+	alternatives [][]comparison
+}
+
+type comparison struct {
+	op      string
+	version Version
+}
+
+// ParseConstraint parses a constraint. Clauses separated by spaces or commas
+// must all match, alternatives separated by "||" need only one to match.
+func ParseConstraint(text string) (Constraint, error) {
+	c := Constraint{text: strings.TrimSpace(text)}
+	for _, alternative := range strings.Split(text, "||") {
+		var comparisons []comparison
+		clauses := strings.Fields(strings.ReplaceAll(alternative, ",", " "))
+		for i := 0; i < len(clauses); i++ {
+			clause := clauses[i]
+			// allow a space between the operator and the version
+			if strings.Trim(clause, "=<>!~^") == "" && i+1 < len(clauses) {
+				i++
+				clause += clauses[i]
+			}
+			expanded, err := parseClause(clause)
+			if err != nil {
+				return Constraint{}, err
+			}
+			comparisons = append(comparisons, expanded...)
+		}
+		c.alternatives = append(c.alternatives, comparisons)
+	}
+	return c, nil
+}
+
+// Check reports whether v satisfies the constraint.
+func (c Constraint) Check(v Version) bool {
+	for _, comparisons := range c.alternatives {
+		matched := true
+		for _, cmp := range comparisons {
+			if !cmp.check(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Constraint) String() string {
+	return c.text
+}
+
+func (cmp comparison) check(v Version) bool {
+	n := v.Compare(cmp.version)
+	switch cmp.op {
+	case "=":
+		return n == 0
+	case "!=":
+		return n != 0
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	case "<":
+		return n < 0
+	default: // "<="
+		return n <= 0
+	}
+}
+
+// parseClause turns one clause into plain comparisons, expanding partial
+// versions, wildcards, tilde and caret ranges.
+func parseClause(clause string) ([]comparison, error) {
+	text := strings.TrimLeft(clause, "=<>!~^")
+	op := clause[:len(clause)-len(text)]
+	v, parts, err := parsePartialVersion(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q: %v", clause, err)
+	}
+	// upper is the first version after the specified parts, e.g. 1.3.0 for 1.2
+	upper := func(parts int) Version {
+		switch parts {
+		case 1:
+			return Version{v.Major + 1, 0, 0}
+		case 2:
+			return Version{v.Major, v.Minor + 1, 0}
+		default:
+			return Version{v.Major, v.Minor, v.Patch + 1}
+		}
+	}
+
+	switch op {
+	case "", "=", "==":
+		if parts == 0 {
+			return nil, nil
+		}
+		if parts == 3 {
+			return []comparison{{"=", v}}, nil
+		}
+		return []comparison{{">=", v}, {"<", upper(parts)}}, nil
+	case "!=":
+		return []comparison{{"!=", v}}, nil
+	case ">":
+		if parts == 0 {
+			return nil, fmt.Errorf("invalid version constraint %q", clause)
+		}
+		if parts < 3 {
+			// >1.2 means >=1.3.0
+			return []comparison{{">=", upper(parts)}}, nil
+		}
+		return []comparison{{op, v}}, nil
+	case "<=":
+		if parts == 0 {
+			return nil, nil
+		}
+		if parts < 3 {
+			// <=1.2 means <1.3.0
+			return []comparison{{"<", upper(parts)}}, nil
+		}
+		return []comparison{{op, v}}, nil
+	case ">=", "<":
+		return []comparison{{op, v}}, nil
+	case "~":
+		if parts == 1 {
+			return []comparison{{">=", v}, {"<", upper(1)}}, nil
+		}
+		return []comparison{{">=", v}, {"<", upper(2)}}, nil
+	case "^":
+		switch {
+		case v.Major > 0 || parts == 1:
+			return []comparison{{">=", v}, {"<", upper(1)}}, nil
+		case v.Minor > 0 || parts == 2:
+			return []comparison{{">=", v}, {"<", upper(2)}}, nil
+		default:
+			return []comparison{{">=", v}, {"<", upper(3)}}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown operator %q in version constraint %q", op, clause)
+	}
+}
+
+// parsePartialVersion parses versions such as v1, 1.2, 1.2.x or 1.2.3 and
+// returns how many parts were given before a wildcard.
+func parsePartialVersion(text string) (Version, int, error) {
+	var numbers [3]int
+	parts := 0
+	for i, part := range strings.SplitN(strings.TrimPrefix(text, "v"), ".", 3) {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, 0, fmt.Errorf("invalid number %q", part)
+		}
+		numbers[i] = n
+		parts++
+	}
+	return Version{numbers[0], numbers[1], numbers[2]}, parts, nil
+}
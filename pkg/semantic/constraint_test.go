@@ -0,0 +1,66 @@
+package semantic
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matches    []string
+		rejects    []string
+	}{
+		{">=1.2.0 <2.0.0", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{">= 1.2, < 2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{"~1.4", []string{"1.4.0", "1.4.7"}, []string{"1.3.9", "1.5.0"}},
+		{"~1.4.2", []string{"1.4.2", "1.4.7"}, []string{"1.4.1", "1.5.0"}},
+		{"^2.1.0", []string{"2.1.0", "2.9.0"}, []string{"2.0.9", "3.0.0"}},
+		{"^0.3.1", []string{"0.3.1", "0.3.9"}, []string{"0.3.0", "0.4.0"}},
+		{"1.3.x", []string{"1.3.0", "1.3.5"}, []string{"1.2.9", "1.4.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{"v1.0.0 || >=3", []string{"1.0.0", "3.1.0"}, []string{"1.0.1", "2.0.0"}},
+		{"*", []string{"0.0.1", "9.9.9"}, nil},
+	}
+	for _, tc := range tests {
+		c, err := ParseConstraint(tc.constraint)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.constraint, err)
+			continue
+		}
+		for _, version := range tc.matches {
+			if _, _, v, _ := ExtractVersionFromTag(version); !c.Check(v) {
+				t.Errorf("%s: expected %s to match", tc.constraint, version)
+			}
+		}
+		for _, version := range tc.rejects {
+			if _, _, v, _ := ExtractVersionFromTag(version); c.Check(v) {
+				t.Errorf("%s: expected %s not to match", tc.constraint, version)
+			}
+		}
+	}
+}
+
+func TestConstraintNegative(t *testing.T) {
+	for _, constraint := range []string{">=one", "=>1.0.0", "1.2.3.4"} {
+		if _, err := ParseConstraint(constraint); err == nil {
+			t.Errorf("expected error for %q, got nil", constraint)
+		}
+	}
+}
+
+func TestComparePrerelease(t *testing.T) {
+	ordered := []string{"alpha", "alpha.1", "alpha.beta", "beta", "beta.2", "beta.11", "rc.1", ""}
+	shuffled := []string{"rc.1", "", "beta.11", "alpha", "beta", "alpha.beta", "beta.2", "alpha.1"}
+	sort.Slice(shuffled, func(i, j int) bool {
+		return ComparePrerelease(shuffled[i], shuffled[j]) < 0
+	})
+	if strings.Join(shuffled, " ") != strings.Join(ordered, " ") {
+		t.Errorf("expected %v, got %v", ordered, shuffled)
+	}
+	if Prerelease("-rc.1+build.5") != "rc.1" || Prerelease("+build.5") != "" {
+		t.Errorf("unexpected pre-release parse")
+	}
+}
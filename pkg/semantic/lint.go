@@ -0,0 +1,49 @@
+package semantic
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultCommitTypes are the types accepted when no list is configured.
+var DefaultCommitTypes = []string{
+	"feat", "fix", "breaking", "chore", "docs", "style", "refactor", "perf", "test", "build", "ci", "revert",
+}
+
+// CommitRules are the policy checks applied on top of the commit grammar.
+// Empty lists and a zero MaxHeaderLength disable the corresponding check.
+type CommitRules struct {
+	Types           []string
+	Scopes          []string
+	MaxHeaderLength int
+	RequiredFooters []string
+}
+
+// Lint returns every rule the message breaks, or nil when it is valid.
+func (rules CommitRules) Lint(message string) []string {
+	var violations []string
+	c, err := ParseCommit(message)
+	if c.Header == "" {
+		return []string{"commit message is empty"}
+	}
+	if rules.MaxHeaderLength > 0 && len(c.Header) > rules.MaxHeaderLength {
+		violations = append(violations, fmt.Sprintf("header is %d characters long, the maximum is %d", len(c.Header), rules.MaxHeaderLength))
+	}
+	if err != nil {
+		violations = append(violations, err.Error())
+	} else {
+		if len(rules.Types) > 0 && !slices.Contains(rules.Types, c.Type) {
+			violations = append(violations, fmt.Sprintf("type %q is not one of %s", c.Type, strings.Join(rules.Types, ", ")))
+		}
+		if len(rules.Scopes) > 0 && c.Scope != "" && !slices.Contains(rules.Scopes, c.Scope) {
+			violations = append(violations, fmt.Sprintf("scope %q is not one of %s", c.Scope, strings.Join(rules.Scopes, ", ")))
+		}
+	}
+	for _, token := range rules.RequiredFooters {
+		if _, ok := c.Footer(token); !ok {
+			violations = append(violations, fmt.Sprintf("missing required footer %q", token))
+		}
+	}
+	return violations
+}
@@ -0,0 +1,22 @@
+package semantic
+
+import "testing"
+
+func TestCommitRulesLint(t *testing.T) {
+	rules := CommitRules{
+		Types:           []string{"feat", "fix"},
+		Scopes:          []string{"api"},
+		MaxHeaderLength: 30,
+		RequiredFooters: []string{"Signed-off-by"},
+	}
+
+	valid := "fix(api): handle timeouts\n\nSigned-off-by: A Dev <dev@example.com>"
+	if violations := rules.Lint(valid); len(violations) != 0 {
+		t.Errorf("expected no violations, got %v", violations)
+	}
+
+	invalid := "docs(cli): describe every single flag in the readme"
+	if violations := rules.Lint(invalid); len(violations) != 4 {
+		t.Errorf("expected 4 violations, got %d: %v", len(violations), violations)
+	}
+}
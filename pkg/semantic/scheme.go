@@ -0,0 +1,59 @@
+package semantic
+
+import (
+	"fmt"
+	"time"
+)
+
+// Versioned is a version parsed by a Scheme.
+type Versioned interface {
+	String() string
+	// CompareTo returns a negative number, zero or a positive number when the
+	// version is lower than, equal to or greater than other.
+	CompareTo(other Versioned) int
+}
+
+// Scheme knows how to find, order and increment versions of one style.
+type Scheme interface {
+	Name() string
+	ExtractVersionFromTag(tag string) (string, string, Versioned, error)
+	Increment(current Versioned, bump string, now time.Time) (Versioned, error)
+}
+
+// SemVer is the MAJOR.MINOR.PATCH scheme driven by commit message bumps.
+var SemVer Scheme = semVerScheme{}
+
+// LookupScheme returns the scheme with the given name. The format is only
+// used by schemes that need one (calver).
+func LookupScheme(name, format string) (Scheme, error) {
+	switch name {
+	case "", "semver":
+		return SemVer, nil
+	case "calver":
+		return NewCalVerScheme(format)
+	default:
+		return nil, fmt.Errorf("unknown version scheme %q", name)
+	}
+}
+
+type semVerScheme struct{}
+
+func (semVerScheme) Name() string {
+	return "semver"
+}
+
+func (semVerScheme) ExtractVersionFromTag(tag string) (string, string, Versioned, error) {
+	prefix, suffix, v, err := ExtractVersionFromTag(tag)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return prefix, suffix, v, nil
+}
+
+func (semVerScheme) Increment(current Versioned, bump string, _ time.Time) (Versioned, error) {
+	v, ok := current.(Version)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a semantic version", current)
+	}
+	return v.Increment(bump)
+}
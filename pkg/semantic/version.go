@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 type Version struct {
@@ -39,6 +40,12 @@ func (v Version) Compare(other Version) int {
 	}
 	return v.Patch - other.Patch
 }
+func (v Version) CompareTo(other Versioned) int {
+	if o, ok := other.(Version); ok {
+		return v.Compare(o)
+	}
+	return strings.Compare(v.String(), other.String())
+}
 func (v Version) IsGreaterThan(other Version) bool {
 	return v.Compare(other) > 0
 }
@@ -64,7 +71,7 @@ func (v Version) IsNotEmpty() bool {
 	return !v.IsEmpty()
 }
 
-var versionFmt = regexp.MustCompile(`(.*)(\d+)\.(\d+)\.(\d+)(.*)`)
+var versionFmt = regexp.MustCompile(`(.*?)(\d+)\.(\d+)\.(\d+)(.*)`)
 
 func ExtractVersionFromTag(tag string) (string, string, Version, error) {
 	matches := versionFmt.FindStringSubmatch(tag)
@@ -87,3 +94,45 @@ func ExtractVersionFromTag(tag string) (string, string, Version, error) {
 	}
 	return matches[1], matches[5], v, nil
 }
+
+// Prerelease returns the pre-release part of a tag suffix, e.g. "rc.1" for
+// "-rc.1+build.5". Build metadata alone is not a pre-release.
+func Prerelease(suffix string) string {
+	suffix, _, _ = strings.Cut(suffix, "+")
+	return strings.TrimPrefix(suffix, "-")
+}
+
+// ComparePrerelease orders pre-release identifiers the way semver 2.0 does.
+// The empty string is a release and sorts after every pre-release.
+func ComparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return an - bn
+			}
+		case aErr == nil:
+			// numeric identifiers sort before alphanumeric ones
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(as) - len(bs)
+}
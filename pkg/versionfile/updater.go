@@ -0,0 +1,241 @@
+package versionfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Updater reads and rewrites the version held in one kind of file. Updaters
+// edit the text in place so the rest of the file keeps its formatting.
+type Updater interface {
+	Name() string
+	Match(path string) bool
+	// Versions returns every version string the updater manages in content,
+	// e.g. both version and appVersion for a Helm chart.
+	Versions(content []byte) ([]string, error)
+	Write(content []byte, version string) ([]byte, error)
+}
+
+var updaters []Updater
+
+// Register adds an updater. Updaters registered later take precedence.
+func Register(updater Updater) {
+	updaters = append(updaters, updater)
+}
+
+// Find returns the updater for the file at path.
+func Find(path string) (Updater, error) {
+	for i := len(updaters) - 1; i >= 0; i-- {
+		if updaters[i].Match(path) {
+			return updaters[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no version updater for %s", path)
+}
+
+// WellKnownFiles are looked for when no files are given explicitly.
+var WellKnownFiles = []string{"package.json", "Chart.yaml", "pyproject.toml", "Cargo.toml"}
+
+// DefaultFiles returns the well known version files that exist in dir.
+func DefaultFiles(dir string) []string {
+	var files []string
+	for _, name := range WellKnownFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// ReadVersions returns the versions held in the file at path.
+func ReadVersions(path string) ([]string, error) {
+	updater, err := Find(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	versions, err := updater.Versions(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return versions, nil
+}
+
+// WriteVersion sets the version in the file at path, reporting whether the
+// file changed.
+func WriteVersion(path, version string) (bool, error) {
+	updater, err := Find(path)
+	if err != nil {
+		return false, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %s: %v", path, err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	updated, err := updater.Write(content, version)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", path, err)
+	}
+	if string(updated) == string(content) {
+		return false, nil
+	}
+	if err := os.WriteFile(path, updated, stat.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return true, nil
+}
+
+// Normalize strips a leading "v" from tag style versions such as v1.2.3.
+func Normalize(version string) string {
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') && version[1] >= '0' && version[1] <= '9' {
+		return version[1:]
+	}
+	return version
+}
+
+// regexUpdater replaces the second capture group of each pattern. The first
+// and third groups hold the text around the version and are kept as is.
+type regexUpdater struct {
+	name     string
+	match    func(path string) bool
+	sections []string
+	patterns []*regexp.Regexp
+	optional []bool
+}
+
+func (u *regexUpdater) Name() string {
+	return u.name
+}
+
+func (u *regexUpdater) Match(path string) bool {
+	return u.match(path)
+}
+
+func (u *regexUpdater) Versions(content []byte) ([]string, error) {
+	start, end, err := u.section(content)
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for i, pattern := range u.patterns {
+		matches := pattern.FindSubmatch(content[start:end])
+		if matches == nil {
+			if u.optional[i] {
+				continue
+			}
+			return nil, fmt.Errorf("no version found for the %s format", u.name)
+		}
+		versions = append(versions, string(matches[2]))
+	}
+	return versions, nil
+}
+
+func (u *regexUpdater) Write(content []byte, version string) ([]byte, error) {
+	start, end, err := u.section(content)
+	if err != nil {
+		return nil, err
+	}
+	section := content[start:end]
+	for i, pattern := range u.patterns {
+		loc := pattern.FindSubmatchIndex(section)
+		if loc == nil {
+			if u.optional[i] {
+				continue
+			}
+			return nil, fmt.Errorf("no version found for the %s format", u.name)
+		}
+		updated := make([]byte, 0, len(section)+len(version))
+		updated = append(updated, section[:loc[4]]...)
+		updated = append(updated, version...)
+		updated = append(updated, section[loc[5]:]...)
+		section = updated
+	}
+	result := make([]byte, 0, len(content)+len(version))
+	result = append(result, content[:start]...)
+	result = append(result, section...)
+	result = append(result, content[end:]...)
+	return result, nil
+}
+
+var tomlTableFmt = regexp.MustCompile(`(?m)^\s*\[\[?([^\]]+)\]\]?\s*(#.*)?$`)
+
+// section returns the byte range of the first TOML table named in
+// u.sections, or the whole content when the format has no sections.
+func (u *regexUpdater) section(content []byte) (int, int, error) {
+	if len(u.sections) == 0 {
+		return 0, len(content), nil
+	}
+	tables := tomlTableFmt.FindAllSubmatchIndex(content, -1)
+	for _, name := range u.sections {
+		for i, table := range tables {
+			if strings.TrimSpace(string(content[table[2]:table[3]])) != name {
+				continue
+			}
+			end := len(content)
+			if i+1 < len(tables) {
+				end = tables[i+1][0]
+			}
+			return table[1], end, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("no [%s] table found", strings.Join(u.sections, "] or ["))
+}
+
+func baseNameIs(name string) func(string) bool {
+	return func(path string) bool {
+		return filepath.Base(path) == name
+	}
+}
+
+func init() {
+	Register(&regexUpdater{
+		name:     "package.json",
+		match:    baseNameIs("package.json"),
+		patterns: []*regexp.Regexp{regexp.MustCompile(`(?m)^(\s*"version"\s*:\s*")([^"]*)(")`)},
+		optional: []bool{false},
+	})
+	Register(&regexUpdater{
+		name:  "Chart.yaml",
+		match: baseNameIs("Chart.yaml"),
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?m)^(version:[ \t]*["']?)([^"'\s#]+)(["']?)`),
+			regexp.MustCompile(`(?m)^(appVersion:[ \t]*["']?)([^"'\s#]+)(["']?)`),
+		},
+		optional: []bool{false, true},
+	})
+	Register(&regexUpdater{
+		name:     "pyproject.toml",
+		match:    baseNameIs("pyproject.toml"),
+		sections: []string{"project", "tool.poetry"},
+		patterns: []*regexp.Regexp{regexp.MustCompile(`(?m)^(version[ \t]*=[ \t]*["'])([^"']*)(["'])`)},
+		optional: []bool{false},
+	})
+	Register(&regexUpdater{
+		name:     "Cargo.toml",
+		match:    baseNameIs("Cargo.toml"),
+		sections: []string{"package", "workspace.package"},
+		patterns: []*regexp.Regexp{regexp.MustCompile(`(?m)^(version[ \t]*=[ \t]*")([^"]*)(")`)},
+		optional: []bool{false},
+	})
+	Register(&regexUpdater{
+		name: "go",
+		match: func(path string) bool {
+			return filepath.Ext(path) == ".go"
+		},
+		// only const declarations, on their own or in a const block, so
+		// that variables and assignments named Version are left alone
+		patterns: []*regexp.Regexp{regexp.MustCompile(`(?m)^([ \t]*const(?:[ \t]+|[ \t]*\((?:[^)"]|"[^"\n]*")*?\n[ \t]*)Version(?:[ \t]+string)?[ \t]*=[ \t]*")([^"]*)(")`)},
+		optional: []bool{false},
+	})
+}
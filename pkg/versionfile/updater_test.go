@@ -0,0 +1,97 @@
+package versionfile
+
+import "testing"
+
+func TestUpdaters(t *testing.T) {
+	tests := []struct {
+		path     string
+		input    string
+		expected string
+	}{
+		{
+			path:     "package.json",
+			input:    "{\n  \"name\": \"app\",\n  \"version\": \"1.0.0\",\n  \"dependencies\": {}\n}\n",
+			expected: "{\n  \"name\": \"app\",\n  \"version\": \"1.2.3\",\n  \"dependencies\": {}\n}\n",
+		},
+		{
+			path:     "deploy/Chart.yaml",
+			input:    "apiVersion: v2\nname: app\nversion: 1.0.0 # chart\nappVersion: \"1.0.0\"\n",
+			expected: "apiVersion: v2\nname: app\nversion: 1.2.3 # chart\nappVersion: \"1.2.3\"\n",
+		},
+		{
+			path:     "pyproject.toml",
+			input:    "[build-system]\nrequires = [\"hatchling\"]\n\n[project]\nname = \"app\"\nversion = '1.0.0'\n\n[tool.x]\nversion = \"9\"\n",
+			expected: "[build-system]\nrequires = [\"hatchling\"]\n\n[project]\nname = \"app\"\nversion = '1.2.3'\n\n[tool.x]\nversion = \"9\"\n",
+		},
+		{
+			path:     "Cargo.toml",
+			input:    "[dependencies]\nserde = { version = \"1\" }\n\n[package]\nname = \"app\"\nversion = \"1.0.0\"\n",
+			expected: "[dependencies]\nserde = { version = \"1\" }\n\n[package]\nname = \"app\"\nversion = \"1.2.3\"\n",
+		},
+		{
+			path:     "internal/version.go",
+			input:    "package internal\n\nconst (\n\tName    = \"app\"\n\tVersion = \"1.0.0\"\n)\n",
+			expected: "package internal\n\nconst (\n\tName    = \"app\"\n\tVersion = \"1.2.3\"\n)\n",
+		}, {
+			path:     "version.go",
+			input:    "package main\n\nvar Version = \"dev\"\n\nconst Version string = \"1.0.0\"\n\nfunc init() {\n\tVersion = \"9.9.9\"\n}\n",
+			expected: "package main\n\nvar Version = \"dev\"\n\nconst Version string = \"1.2.3\"\n\nfunc init() {\n\tVersion = \"9.9.9\"\n}\n",
+		},
+		{
+			path:     "cmd/app/version.go",
+			input:    "package main\n\nconst (\n\tUsage   = \"app (beta)\"\n\tVersion = \"1.0.0\"\n)\n",
+			expected: "package main\n\nconst (\n\tUsage   = \"app (beta)\"\n\tVersion = \"1.2.3\"\n)\n",
+		},
+	}
+	for _, tc := range tests {
+		updater, err := Find(tc.path)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.path, err)
+			continue
+		}
+		versions, err := updater.Versions([]byte(tc.input))
+		if err != nil || versions[0] != "1.0.0" {
+			t.Errorf("%s: expected version 1.0.0, got %v (%v)", tc.path, versions, err)
+		}
+		got, err := updater.Write([]byte(tc.input), "1.2.3")
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.path, err)
+			continue
+		}
+		if string(got) != tc.expected {
+			t.Errorf("%s:\n  expected %q\n  got      %q", tc.path, tc.expected, string(got))
+		}
+	}
+}
+
+func TestUpdatersNoMatch(t *testing.T) {
+	tests := []struct {
+		path  string
+		input string
+	}{
+		{"package.json", "{\n  \"name\": \"app\"\n}\n"},
+		{"pyproject.toml", "[tool.poetry.dependencies]\nversion = \"1.0.0\"\n"},
+		{"version.go", "package main\n\nvar Version = \"1.0.0\"\n"},
+		{"main.go", "package main\n\nvar (\n\tVersion = \"1.0.0\"\n)\n\nconst Name = \"app\"\n"},
+		{"main.go", "package main\n\nfunc main() {\n\tVersion = \"1.0.0\"\n}\n\nconst constVersion = \"1.0.0\"\n"},
+	}
+	for _, tc := range tests {
+		updater, err := Find(tc.path)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.path, err)
+			continue
+		}
+		if versions, err := updater.Versions([]byte(tc.input)); err == nil {
+			t.Errorf("%s: expected no version in %q, got %v", tc.path, tc.input, versions)
+		}
+		if _, err := updater.Write([]byte(tc.input), "1.2.3"); err == nil {
+			t.Errorf("%s: expected an error writing to %q", tc.path, tc.input)
+		}
+	}
+}
+
+func TestFindUnknown(t *testing.T) {
+	if _, err := Find("README.md"); err == nil {
+		t.Error("expected error for a file without an updater, got nil")
+	}
+}